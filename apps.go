@@ -0,0 +1,348 @@
+package gothub
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoPrivateKey = errors.New("No RSA private key found in PEM data")
+)
+
+// How long the JWTs we sign are valid for; GitHub allows at most ten minutes.
+const appJWTLifetime = 9 * time.Minute
+
+// How long before it expires that an installation token gets replaced.
+const installationTokenLeeway = time.Minute
+
+// A GitHub App, which authenticates as itself with short-lived JWTs signed by
+// its private key, as described here:
+// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/about-authentication-with-a-github-app
+type App struct {
+	Id int
+
+	key *rsa.PrivateKey
+	g   *GitHub
+
+	// The transport underneath the one that adds the App's JWTs.
+	base http.RoundTripper
+}
+
+// An installation of a GitHub App on a user or organization account.
+type Installation struct {
+	Id                  int               `json:"id"`
+	AppId               int               `json:"app_id"`
+	Account             RepositoryOwner   `json:"account"`
+	TargetType          string            `json:"target_type"`
+	RepositorySelection string            `json:"repository_selection"`
+	AccessTokensUrl     string            `json:"access_tokens_url"`
+	RepositoriesUrl     string            `json:"repositories_url"`
+	HtmlUrl             string            `json:"html_url"`
+	Permissions         map[string]string `json:"permissions"`
+	Events              []string          `json:"events"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+// An installation access token, with which an App acts on an installation's
+// behalf.
+type InstallationToken struct {
+	Token               string            `json:"token"`
+	ExpiresAt           time.Time         `json:"expires_at"`
+	Permissions         map[string]string `json:"permissions"`
+	RepositorySelection string            `json:"repository_selection"`
+}
+
+// Narrows down what an installation token may access. The zero value asks for
+// everything the installation was granted.
+type InstallationTokenOptions struct {
+	Repositories  []string          `json:"repositories,omitempty"`
+	RepositoryIds []int             `json:"repository_ids,omitempty"`
+	Permissions   map[string]string `json:"permissions,omitempty"`
+}
+
+// Sets up authentication as the GitHub App with the given ID, using its
+// private key in PEM format (as downloaded from the App's settings).
+//
+// The options configure the session used to call the App endpoints, and are
+// the same as for New; for GitHub Enterprise Server, that includes
+// WithBaseUrl.
+func NewApp(appId int, privateKeyPem []byte, opts ...Option) (*App, error) {
+	key, err := parseRSAPrivateKey(privateKeyPem)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &App{Id: appId, key: key, g: g, base: g.httpClient.Transport}
+	client := *g.httpClient
+	client.Transport = &appTransport{app: a, base: client.Transport}
+	g.httpClient = &client
+	return a, nil
+}
+
+// Signs a new JWT that identifies the App to the API.
+func (a *App) JWT() (string, error) {
+	now := time.Now()
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	claims := map[string]interface{}{
+		// Backdated, to allow for clock drift between us and GitHub.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.Itoa(a.Id),
+	}
+
+	var segments [2]string
+	for i, v := range []interface{}{header, claims} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		segments[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed := segments[0] + "." + segments[1]
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Lists all of the accounts the App is installed on, returning the response
+// the last page came in.
+func (a *App) Installations(ctx context.Context) ([]Installation, *Response, error) {
+	return listAll(ctx, a.ListInstallations(nil))
+}
+
+// Returns an iterator over the accounts the App is installed on, starting at
+// the page selected by opts (which may be nil).
+func (a *App) ListInstallations(opts *ListOptions) *ListIterator[Installation] {
+	return newListIterator[Installation](a.g, "/app/installations", opts)
+}
+
+// Mints a new access token for the given installation. Pass nil options to get
+// all of the installation's repositories and permissions.
+func (a *App) CreateInstallationToken(ctx context.Context, installationId int, opts *InstallationTokenOptions) (*InstallationToken, error) {
+	if opts == nil {
+		opts = &InstallationTokenOptions{}
+	}
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("/app/installations/%d/access_tokens", installationId)
+	response, err := a.g.httpPost(ctx, uri, nil, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if err := checkStatus(response, http.StatusCreated); err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var token InstallationToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Creates a session that acts on behalf of the given installation. Its
+// installation tokens are minted as needed, and replaced shortly before they
+// expire, so the session can be kept around for as long as necessary.
+//
+// The options are the same as for New, and apply on top of the App's own
+// settings (such as its base URL).
+func (a *App) InstallationSession(installationId int, opts ...Option) (*GitHub, error) {
+	// Start out from the App's own HTTP client, minus the JWTs.
+	client := *a.g.httpClient
+	client.Transport = a.base
+	base := []Option{
+		WithHttpClient(&client),
+		WithBaseUrl(a.g.BaseUrl, a.g.UploadUrl),
+		WithUserAgent(a.g.UserAgent),
+	}
+
+	g, err := New(append(base, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := *g.httpClient
+	wrapped.Transport = a.InstallationTransport(installationId, nil, g.httpClient.Transport)
+	g.httpClient = &wrapped
+
+	// The installation token is the only credential the session may use.
+	g.Authorization = ""
+	return g, nil
+}
+
+// Returns a transport that authenticates requests as the given installation,
+// and sends them through base (or http.DefaultTransport, if base is nil).
+func (a *App) InstallationTransport(installationId int, opts *InstallationTokenOptions, base http.RoundTripper) *InstallationTransport {
+	return &InstallationTransport{app: a, installationId: installationId, opts: opts, base: base}
+}
+
+// A transport that authenticates requests as a GitHub App installation,
+// caching the installation's access token until shortly before it expires,
+// or until the API turns it down. It is safe for concurrent use.
+type InstallationTransport struct {
+	app            *App
+	installationId int
+	opts           *InstallationTokenOptions
+	base           http.RoundTripper
+
+	mu      sync.Mutex
+	token   *InstallationToken
+	minting *tokenMint
+}
+
+// A new installation token on its way, which the requests that need it wait
+// for together.
+type tokenMint struct {
+	done  chan struct{}
+	token *InstallationToken
+	err   error
+}
+
+// Returns a valid installation token, minting a new one if the cached token is
+// missing or about to expire. Only one token is minted at a time; requests
+// that need one while it is on its way wait for it, or until ctx is done.
+func (t *InstallationTransport) Token(ctx context.Context) (*InstallationToken, error) {
+	t.mu.Lock()
+	if t.token != nil && time.Until(t.token.ExpiresAt) > installationTokenLeeway {
+		defer t.mu.Unlock()
+		return t.token, nil
+	}
+	if m := t.minting; m != nil {
+		t.mu.Unlock()
+		select {
+		case <-m.done:
+			// Should the request minting the token have been cut short,
+			// another one is minted for this one.
+			if ctx.Err() == nil && (errors.Is(m.err, context.Canceled) || errors.Is(m.err, context.DeadlineExceeded)) {
+				return t.Token(ctx)
+			}
+			return m.token, m.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	m := &tokenMint{done: make(chan struct{})}
+	t.minting = m
+	t.mu.Unlock()
+
+	// The lock is not held while the token is minted, so that requests that
+	// do not need a new token are not held up by it.
+	m.token, m.err = t.app.CreateInstallationToken(ctx, t.installationId, t.opts)
+
+	t.mu.Lock()
+	if m.err == nil {
+		t.token = m.token
+	}
+	t.minting = nil
+	t.mu.Unlock()
+	close(m.done)
+	return m.token, m.err
+}
+
+// Drops the cached token, if it is still the given one, so that the next
+// request mints a new one.
+func (t *InstallationTransport) forget(token *InstallationToken) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == token {
+		t.token = nil
+	}
+}
+
+func (t *InstallationTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, err := t.Token(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", fmt.Sprintf("token %s", token.Token))
+	response, err := roundTripper(t.base).RoundTrip(r)
+	if err == nil && response.StatusCode == http.StatusUnauthorized {
+		// The token was revoked before it expired.
+		t.forget(token)
+	}
+	return response, err
+}
+
+// A transport that authenticates requests as the App itself.
+type appTransport struct {
+	app  *App
+	base http.RoundTripper
+}
+
+func (t *appTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	jwt, err := t.app.JWT()
+	if err != nil {
+		return nil, err
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+	return roundTripper(t.base).RoundTrip(r)
+}
+
+// Returns rt, or http.DefaultTransport if rt is nil.
+func roundTripper(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		return http.DefaultTransport
+	}
+	return rt
+}
+
+// Parses a PKCS #1 or PKCS #8 encoded RSA private key out of PEM data.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNoPrivateKey
+	}
+	return key, nil
+}
@@ -0,0 +1,308 @@
+package gothub
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Generates a private key for a test App, in PEM format.
+func newAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, data
+}
+
+// Checks the signature and claims of an App's JWT.
+func verifyAppJWT(key *rsa.PublicKey, jwt string, appId int) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWT %q", jwt)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+	if claims.Iss != fmt.Sprint(appId) {
+		return fmt.Errorf("unexpected issuer %q", claims.Iss)
+	}
+	now := time.Now().Unix()
+	if claims.Iat > now || claims.Exp <= now || claims.Exp-claims.Iat > 600 {
+		return fmt.Errorf("bad JWT lifetime %d-%d", claims.Iat, claims.Exp)
+	}
+	return nil
+}
+
+// A fake GitHub that serves the App endpoints, handing out installation tokens
+// that expire after the given lifetime. It is closed once the test is over.
+type appServer struct {
+	*httptest.Server
+	key      *rsa.PublicKey
+	lifetime time.Duration
+
+	mu      sync.Mutex
+	minted  int
+	revoked string
+}
+
+func newAppServer(t *testing.T, key *rsa.PublicKey, lifetime time.Duration) *appServer {
+	s := &appServer{key: key, lifetime: lifetime}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		auth := r.Header.Get("Authorization")
+
+		if strings.HasPrefix(r.URL.Path, "/app/") {
+			if err := verifyAppJWT(s.key, strings.TrimPrefix(auth, "Bearer "), 1234); err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		switch {
+		case r.URL.Path == "/app/installations":
+			// Two pages, of an installation each.
+			if r.URL.Query().Get("page") == "2" {
+				fmt.Fprint(w, `[{"id": 43, "app_id": 1234, "account": {"login": "octocat"}, "target_type": "User"}]`)
+				return
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/app/installations?page=2>; rel="next"`, r.Host))
+			fmt.Fprint(w, `[{"id": 42, "app_id": 1234, "account": {"login": "octo-org"}, "target_type": "Organization"}]`)
+
+		case r.Method == "POST" && r.URL.Path == "/app/installations/42/access_tokens":
+			s.mu.Lock()
+			s.minted++
+			n := s.minted
+			s.mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			expires := time.Now().Add(s.lifetime).UTC().Format(time.RFC3339)
+			fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, n, expires)
+
+		case r.URL.Path == "/orgs/octo-org":
+			s.mu.Lock()
+			revoked := s.revoked != "" && auth == "token "+s.revoked
+			s.mu.Unlock()
+			if revoked {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"message": "Bad credentials"}`)
+				return
+			}
+			if !strings.HasPrefix(auth, "token ghs_") {
+				t.Errorf("Unexpected Authorization %q", auth)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"login": "octo-org"}`)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestAppJWT(t *testing.T) {
+	key, data := newAppKey(t)
+	app, err := NewApp(1234, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt, err := app.JWT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyAppJWT(&key.PublicKey, jwt, 1234); err != nil {
+		t.Error(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewApp(1234, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})); err != nil {
+		t.Errorf("PKCS #8 keys should be accepted: %s", err)
+	}
+	if _, err := NewApp(1234, []byte("not a key")); err != ErrNoPrivateKey {
+		t.Errorf("Expected ErrNoPrivateKey, got %v", err)
+	}
+}
+
+func TestAppInstallations(t *testing.T) {
+	key, data := newAppKey(t)
+	server := newAppServer(t, &key.PublicKey, time.Hour)
+
+	app, err := NewApp(1234, data, WithBaseUrl(server.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installations, resp, err := app.Installations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installations) != 2 || installations[0].Id != 42 || installations[0].Account.Login != "octo-org" || installations[1].Id != 43 {
+		t.Errorf("Unexpected installations %+v", installations)
+	}
+	if resp == nil || resp.NextPage != 0 {
+		t.Errorf("Expected the response of the last page, got %+v", resp)
+	}
+
+	it := app.ListInstallations(&ListOptions{Page: 2})
+	if !it.Next(context.Background()) || it.Value().Id != 43 || it.Next(context.Background()) {
+		t.Errorf("Expected only the second page, got %v", it.Err())
+	}
+
+	token, err := app.CreateInstallationToken(context.Background(), 42, &InstallationTokenOptions{Repositories: []string{"gothub"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Token != "ghs_1" || time.Until(token.ExpiresAt) < 59*time.Minute {
+		t.Errorf("Unexpected installation token %+v", token)
+	}
+}
+
+func TestInstallationSessionCachesTokens(t *testing.T) {
+	key, data := newAppKey(t)
+	server := newAppServer(t, &key.PublicKey, time.Hour)
+
+	app, err := NewApp(1234, data, WithBaseUrl(server.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := app.InstallationSession(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := g.GetOrganization("octo-org"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if server.minted != 1 {
+		t.Errorf("Expected a single installation token to be minted, got %d", server.minted)
+	}
+}
+
+func TestInstallationSessionRefreshesTokens(t *testing.T) {
+	key, data := newAppKey(t)
+
+	// Tokens that are already inside the leeway are replaced on every request.
+	server := newAppServer(t, &key.PublicKey, 30*time.Second)
+
+	app, err := NewApp(1234, data, WithBaseUrl(server.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := app.InstallationSession(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := g.GetOrganization("octo-org"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if server.minted != 3 {
+		t.Errorf("Expected an installation token to be minted per request, got %d", server.minted)
+	}
+}
+
+func TestInstallationSessionReplacesRevokedTokens(t *testing.T) {
+	key, data := newAppKey(t)
+	server := newAppServer(t, &key.PublicKey, time.Hour)
+
+	app, err := NewApp(1234, data, WithBaseUrl(server.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := app.InstallationSession(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.GetOrganization("octo-org"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The token is revoked long before it expires; the request that finds
+	// out fails, and the next one gets a new token.
+	server.mu.Lock()
+	server.revoked = "ghs_1"
+	server.mu.Unlock()
+	if _, _, err := g.GetOrganization("octo-org"); err == nil {
+		t.Fatal("Expected the revoked token to be turned down")
+	}
+	if _, _, err := g.GetOrganization("octo-org"); err != nil {
+		t.Fatal(err)
+	}
+	if server.minted != 2 {
+		t.Errorf("Expected a new installation token to be minted, got %d", server.minted)
+	}
+}
+
+func TestInstallationSessionMintsTokensOnce(t *testing.T) {
+	key, data := newAppKey(t)
+	server := newAppServer(t, &key.PublicKey, time.Hour)
+
+	app, err := NewApp(1234, data, WithBaseUrl(server.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := app.InstallationSession(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := g.GetOrganization("octo-org"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if server.minted != 1 {
+		t.Errorf("Expected the requests to share an installation token, got %d", server.minted)
+	}
+}
@@ -0,0 +1,241 @@
+package gothub
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Stores responses from the API, so that they can be revalidated with
+// conditional requests, rather than fetched (and counted against the rate
+// limit) again. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Returns the response stored under the key, if there is one.
+	Get(key string) (response []byte, ok bool)
+
+	// Stores the response under the key, replacing any previous one.
+	Set(key string, response []byte)
+
+	// Removes the response stored under the key, if there is one.
+	Delete(key string)
+}
+
+// The header set on responses that were served out of a Cache.
+const CacheHeader string = "X-From-Cache"
+
+// A transport that makes conditional requests for the responses it has
+// cached, as described here:
+// http://developer.github.com/v3/#conditional-requests
+//
+// When the API answers with an HTTP 304 Not Modified (which does not count
+// against the rate limit), the cached response is handed back instead, with
+// the headers of the 304 (such as the rate limit headers) on top of it.
+type CacheTransport struct {
+	Cache Cache
+
+	// The transport that requests are sent through; http.DefaultTransport
+	// when nil.
+	Transport http.RoundTripper
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+	if req.Method != "GET" && req.Method != "HEAD" {
+		response, err := roundTripper(t.Transport).RoundTrip(req)
+		if err == nil && response.StatusCode < 400 {
+			// Whatever we had cached for the resource is probably stale now.
+			t.Cache.Delete(cacheKey(cacheable(req)))
+		}
+		return response, err
+	}
+
+	var cached *http.Response
+	if data, ok := t.Cache.Get(key); ok {
+		if r, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req); err == nil {
+			cached = r
+		} else {
+			t.Cache.Delete(key)
+		}
+	}
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	response, err := roundTripper(t.Transport).RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && response.StatusCode == http.StatusNotModified {
+		response.Body.Close()
+		for name, values := range response.Header {
+			cached.Header[name] = values
+		}
+		t.store(key, cached)
+		cached.Header.Set(CacheHeader, "1")
+		return cached, nil
+	}
+
+	if response.StatusCode == http.StatusOK &&
+		(response.Header.Get("ETag") != "" || response.Header.Get("Last-Modified") != "") {
+		t.store(key, response)
+	}
+	return response, nil
+}
+
+// Stores the response, leaving its body readable.
+func (t *CacheTransport) store(key string, response *http.Response) {
+	data, err := httputil.DumpResponse(response, true)
+	if err != nil {
+		return
+	}
+	t.Cache.Set(key, data)
+}
+
+// Responses differ by the credentials they were requested with, and the media
+// type that was asked for, so those go into the key too (hashed, so the
+// credentials do not end up in the cache).
+func cacheKey(req *http.Request) string {
+	vary := sha256.Sum256([]byte(req.Header.Get("Authorization") + "\n" + req.Header.Get("Accept")))
+	return req.Method + " " + req.URL.String() + " " + hex.EncodeToString(vary[:8])
+}
+
+// Returns the GET request whose cached response a request to the same URL
+// would change.
+func cacheable(req *http.Request) *http.Request {
+	get := req.Clone(req.Context())
+	get.Method = "GET"
+	return get
+}
+
+// A Cache that keeps up to a fixed number of responses in memory, evicting the
+// least recently used ones first.
+type MemoryCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key      string
+	response []byte
+}
+
+// Creates a MemoryCache that holds up to size responses.
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).response, true
+}
+
+func (c *MemoryCache) Set(key string, response []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*memoryCacheEntry).response = response
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{key: key, response: response})
+	for c.size > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// Returns the number of responses in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// A Cache that keeps responses as files in a directory, so they outlive the
+// process. Failures to read or write the files are treated as cache misses.
+type DiskCache struct {
+	dir string
+}
+
+// Creates a DiskCache in the given directory, creating it if need be.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *DiskCache) Set(key string, response []byte) {
+	// Write to a temporary file first, so that readers never see half of a
+	// response.
+	f, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(response)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
@@ -0,0 +1,172 @@
+package gothub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// A fake GitHub that serves a user with an ETag, and answers conditional
+// requests for it with an HTTP 304.
+type etagServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	login       string
+	requests    int
+	notModified int
+}
+
+// Starts an etagServer, and returns it along with a session pointed at it,
+// made with the given options as well.
+func newEtagServer(t *testing.T, opts ...Option) (*GitHub, *etagServer) {
+	s := &etagServer{login: "octocat"}
+	g, server := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+
+		if r.Method == "PATCH" {
+			s.login = "monalisa"
+			fmt.Fprintf(w, `{"login": %q}`, s.login)
+			return
+		}
+
+		etag := fmt.Sprintf(`"%s-%s"`, s.login, r.Header.Get("Authorization"))
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(5000-s.requests))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, `{"login": %q}`, s.login)
+	}, opts...)
+	s.Server = server
+	return g, s
+}
+
+func TestCacheConditionalRequests(t *testing.T) {
+	cache := NewMemoryCache(10)
+	g, server := newEtagServer(t, WithCache(cache))
+
+	for i := 0; i < 3; i++ {
+		user, _, err := g.GetCurrentUser()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Login != "octocat" {
+			t.Errorf("Expected octocat, got %q", user.Login)
+		}
+	}
+
+	if server.notModified != 2 {
+		t.Errorf("Expected 2 conditional hits, got %d", server.notModified)
+	}
+	if g.RateLimitRemaining != 4997 {
+		t.Errorf("The rate limit headers of the 304 were not used: %d", g.RateLimitRemaining)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 cached response, got %d", cache.Len())
+	}
+}
+
+func TestCacheVariesByCredentials(t *testing.T) {
+	_, server := newEtagServer(t)
+
+	cache := NewMemoryCache(10)
+	for _, token := range []string{"one", "two", "one"} {
+		g, err := New(WithBaseUrl(server.URL, ""), WithToken(token), WithCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := g.GetCurrentUser(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if cache.Len() != 2 || server.notModified != 1 {
+		t.Errorf("Expected 2 cached responses and 1 conditional hit, got %d and %d", cache.Len(), server.notModified)
+	}
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	cache := NewMemoryCache(10)
+	g, _ := newEtagServer(t, WithCache(cache))
+	if _, _, err := g.GetCurrentUser(); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := g.httpPatch(context.Background(), "/user", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if cache.Len() != 0 {
+		t.Error("Writing to a resource should drop its cached response")
+	}
+	if user, _, err := g.GetCurrentUser(); err != nil {
+		t.Fatal(err)
+	} else if user.Login != "monalisa" {
+		t.Errorf("Expected monalisa, got %q", user.Login)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	_, server := newEtagServer(t)
+
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		// A new cache each time, as if the process had been restarted.
+		cache, err := NewDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := New(WithBaseUrl(server.URL, ""), WithCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user, _, err := g.GetCurrentUser(); err != nil {
+			t.Fatal(err)
+		} else if user.Login != "octocat" {
+			t.Errorf("Expected octocat, got %q", user.Login)
+		}
+	}
+	if server.notModified != 1 {
+		t.Errorf("Expected 1 conditional hit, got %d", server.notModified)
+	}
+
+	cache, _ := NewDiskCache(dir)
+	cache.Set("key", []byte("value"))
+	if v, ok := cache.Get("key"); !ok || string(v) != "value" {
+		t.Errorf("Expected value, got %q", v)
+	}
+	cache.Delete("key")
+	if _, ok := cache.Get("key"); ok {
+		t.Error("The response should have been deleted")
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	cache.Get("a")
+	cache.Set("c", []byte("3"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("The least recently used response should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected %q to still be cached", key)
+		}
+	}
+}
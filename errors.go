@@ -0,0 +1,149 @@
+package gothub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// An error reported by the GitHub API, as described here:
+// http://developer.github.com/v3/#client-errors
+//
+// Use errors.As to get at it, or one of the IsNotFound, IsValidation and
+// IsRateLimit helpers to check for the common cases.
+type ErrorResponse struct {
+	// The response that carried the error. Its body has already been read,
+	// but can be read again.
+	Response *http.Response `json:"-"`
+
+	Message          string  `json:"message"`
+	DocumentationUrl string  `json:"documentation_url"`
+	Errors           []Error `json:"errors"`
+}
+
+func (e *ErrorResponse) Error() string {
+	msg := fmt.Sprintf("GitHub API responded with HTTP %d", e.Response.StatusCode)
+	if r := e.Response.Request; r != nil {
+		msg = fmt.Sprintf("%s %s: %s", r.Method, r.URL, msg)
+	}
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	if len(e.Errors) > 0 {
+		msg = fmt.Sprintf("%s %+v", msg, e.Errors)
+	}
+	return msg
+}
+
+// Lets errors.Is(err, ErrRateLimitReached) match the API refusing a request
+// because the rate limit was reached.
+func (e *ErrorResponse) Is(target error) bool {
+	return target == ErrRateLimitReached && e.rateLimited()
+}
+
+func (e *ErrorResponse) rateLimited() bool {
+	switch e.Response.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return false
+	}
+	return e.Response.Header.Get("X-RateLimit-Remaining") == "0" ||
+		strings.Contains(strings.ToLower(e.Message), "rate limit")
+}
+
+// A single validation error, describing what was wrong with a field of a
+// request, as described here:
+// http://developer.github.com/v3/#client-errors
+type Error struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func (e Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s.%s: %s", e.Resource, e.Field, e.Code)
+}
+
+// Some endpoints report their validation errors as plain strings, rather than
+// objects.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		*e = Error{Message: message}
+		return nil
+	}
+
+	type plain Error
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// Reports whether err is the API saying that a resource does not exist (or
+// that the session is not allowed to know that it does), including a GraphQL
+// query failing because of a missing object.
+func IsNotFound(err error) bool {
+	if hasStatus(err, http.StatusNotFound) {
+		return true
+	}
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		for _, ee := range e.Errors {
+			if ee.Resource == "GraphQL" && ee.Code == "NOT_FOUND" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reports whether err is the API rejecting the fields of a request.
+func IsValidation(err error) bool {
+	return hasStatus(err, http.StatusUnprocessableEntity)
+}
+
+// Reports whether err is due to the rate limit being reached, either before
+// the request was made (ErrRateLimitReached), or by the API refusing it.
+func IsRateLimit(err error) bool {
+	return errors.Is(err, ErrRateLimitReached)
+}
+
+func hasStatus(err error, status int) bool {
+	var e *ErrorResponse
+	return errors.As(err, &e) && e.Response.StatusCode == status
+}
+
+// Builds an *ErrorResponse out of the response, reading whatever details the
+// API put in its body.
+func newErrorResponse(r *http.Response) *ErrorResponse {
+	e := &ErrorResponse{Response: r}
+	if r.Body == nil {
+		return e
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err == nil && len(body) > 0 {
+		// Not every error comes with a JSON body; we make do without one.
+		json.Unmarshal(body, e)
+	}
+	return e
+}
+
+// Returns an *ErrorResponse unless the response has one of the expected
+// status codes.
+func checkStatus(r *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if r.StatusCode == status {
+			return nil
+		}
+	}
+	return newErrorResponse(r)
+}
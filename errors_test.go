@@ -0,0 +1,155 @@
+package gothub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Starts a fake GitHub that fails most requests, each in its own way, and
+// returns it along with a session pointed at it.
+func newErrorServer(t *testing.T) (*GitHub, *httptest.Server) {
+	return newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		switch r.URL.Path {
+		case "/":
+			// Without a Location, the redirect is handed back as it is.
+			w.WriteHeader(http.StatusMovedPermanently)
+			fmt.Fprint(w, `{"message": "Moved Permanently", "documentation_url": "https://docs.github.com/rest"}`)
+
+		case "/users/nobody":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`)
+
+		case "/user/keys":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "Validation Failed", "errors": [
+				{"resource": "PublicKey", "field": "key", "code": "custom", "message": "key is already in use"},
+				{"resource": "PublicKey", "field": "title", "code": "missing_field"},
+				"key is invalid"
+			]}`)
+
+		case "/orgs/github":
+			w.Header().Set("X-RateLimit-Limit", "60")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded for 127.0.0.1."}`)
+
+		case "/user/following/octocat":
+			w.WriteHeader(http.StatusNoContent)
+
+		case "/user/following/nobody":
+			w.WriteHeader(http.StatusNotFound)
+
+		case "/user/emails":
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, `<html>Bad gateway</html>`)
+
+		default:
+			t.Errorf("Unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestErrorResponseNotFound(t *testing.T) {
+	g, _ := newErrorServer(t)
+
+	_, _, err := g.GetUser("nobody")
+	if !IsNotFound(err) {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		t.Fatal("Expected an *ErrorResponse")
+	}
+	if e.Message != "Not Found" || e.DocumentationUrl != "https://docs.github.com/rest" {
+		t.Errorf("Unexpected error details %+v", e)
+	}
+	if !strings.Contains(e.Error(), "/users/nobody") || !strings.Contains(e.Error(), "404") {
+		t.Errorf("Unhelpful error message %q", e.Error())
+	}
+	if IsValidation(err) || IsRateLimit(err) {
+		t.Error("A not found error is neither a validation nor a rate limit error")
+	}
+}
+
+func TestErrorResponseLogin(t *testing.T) {
+	_, server := newErrorServer(t)
+
+	_, err := login(WithBaseUrl(server.URL, ""))
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected an *ErrorResponse, got %v", err)
+	}
+	if e.Message != "Moved Permanently" || e.Response.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("Expected the API's message, got %q", e.Message)
+	}
+}
+
+func TestErrorResponseValidation(t *testing.T) {
+	g, _ := newErrorServer(t)
+
+	_, _, err := g.AddPublicKey("gothub test key", "ssh-rsa AAAA")
+	if !IsValidation(err) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	var e *ErrorResponse
+	errors.As(err, &e)
+	expected := []Error{
+		{Resource: "PublicKey", Field: "key", Code: "custom", Message: "key is already in use"},
+		{Resource: "PublicKey", Field: "title", Code: "missing_field"},
+		{Message: "key is invalid"},
+	}
+	if len(e.Errors) != len(expected) {
+		t.Fatalf("Unexpected validation errors %+v", e.Errors)
+	}
+	for i := range expected {
+		if e.Errors[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], e.Errors[i])
+		}
+	}
+	if e.Errors[1].Error() != "PublicKey.title: missing_field" {
+		t.Errorf("Unexpected message %q", e.Errors[1].Error())
+	}
+}
+
+func TestErrorResponseRateLimit(t *testing.T) {
+	g, _ := newErrorServer(t)
+
+	_, _, err := g.GetOrganization("github")
+	if !IsRateLimit(err) || !errors.Is(err, ErrRateLimitReached) {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+
+	// Now that the session knows it is out of calls, it does not even try.
+	if _, _, err := g.GetOrganization("github"); err != ErrRateLimitReached {
+		t.Errorf("Expected ErrRateLimitReached, got %v", err)
+	}
+}
+
+func TestErrorResponseWithoutJSON(t *testing.T) {
+	g, _ := newErrorServer(t)
+
+	_, _, err := g.Emails()
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.Response.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Expected an HTTP 502 error, got %v", err)
+	}
+}
+
+func TestIsFollowingNotFound(t *testing.T) {
+	g, _ := newErrorServer(t)
+
+	if following, _, err := g.IsFollowing("octocat"); err != nil || !following {
+		t.Errorf("Expected to be following octocat: %t, %v", following, err)
+	}
+	if following, _, err := g.IsFollowing("nobody"); err != nil || following {
+		t.Errorf("Expected not to be following nobody: %t, %v", following, err)
+	}
+}
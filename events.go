@@ -0,0 +1,359 @@
+package gothub
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// The payload of a webhook delivery, decoded into the struct for its event,
+// such as *PushEvent. See WebhookHandler.
+type WebhookEvent interface {
+	// Returns the name of the event, as sent in the X-GitHub-Event header
+	// (e.g. "push").
+	EventName() string
+}
+
+// The fields that most webhook payloads have in common. Repository is nil for
+// events that are not about a repository, and Organization and Installation
+// are nil unless the event happened in an organization, or was delivered to
+// a GitHub App.
+type WebhookPayload struct {
+	// What happened, for events that tell several things apart (e.g.
+	// "opened" or "closed" for a pull request).
+	Action string `json:"action"`
+
+	Sender       User          `json:"sender"`
+	Repository   *Repository   `json:"repository"`
+	Organization *Organization `json:"organization"`
+	Installation *Installation `json:"installation"`
+}
+
+// Sent when a webhook is set up, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#ping
+type PingEvent struct {
+	WebhookPayload
+	Zen    string `json:"zen"`
+	HookId int    `json:"hook_id"`
+}
+
+// Sent when commits are pushed to a branch, or tags to a repository, as
+// described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type PushEvent struct {
+	WebhookPayload
+	Ref        string       `json:"ref"`
+	Before     string       `json:"before"`
+	After      string       `json:"after"`
+	BaseRef    string       `json:"base_ref"`
+	Created    bool         `json:"created"`
+	Deleted    bool         `json:"deleted"`
+	Forced     bool         `json:"forced"`
+	Compare    string       `json:"compare"`
+	Commits    []PushCommit `json:"commits"`
+	HeadCommit *PushCommit  `json:"head_commit"`
+	Pusher     CommitAuthor `json:"pusher"`
+}
+
+// A commit that was pushed.
+type PushCommit struct {
+	Id        string       `json:"id"`
+	TreeId    string       `json:"tree_id"`
+	Distinct  bool         `json:"distinct"`
+	Message   string       `json:"message"`
+	Timestamp time.Time    `json:"timestamp"`
+	Url       string       `json:"url"`
+	Author    CommitAuthor `json:"author"`
+	Committer CommitAuthor `json:"committer"`
+	Added     []string     `json:"added"`
+	Removed   []string     `json:"removed"`
+	Modified  []string     `json:"modified"`
+}
+
+// The Git author or committer of a commit, or the user who pushed it.
+type CommitAuthor struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// The repository of a push event has its timestamps as Unix times, unlike
+// those of every other payload, so they are turned into strings that
+// Repository can take.
+func (e *PushEvent) UnmarshalJSON(data []byte) error {
+	type plain PushEvent
+	var p struct {
+		*plain
+		Repository map[string]json.RawMessage `json:"repository"`
+	}
+	p.plain = (*plain)(e)
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Repository == nil {
+		e.Repository = nil
+		return nil
+	}
+
+	for _, field := range []string{"created_at", "pushed_at"} {
+		seconds, err := strconv.ParseInt(string(bytes.TrimSpace(p.Repository[field])), 10, 64)
+		if err != nil {
+			continue
+		}
+		p.Repository[field], _ = json.Marshal(time.Unix(seconds, 0).UTC())
+	}
+	repository, err := json.Marshal(p.Repository)
+	if err != nil {
+		return err
+	}
+	e.Repository = new(Repository)
+	return json.Unmarshal(repository, e.Repository)
+}
+
+// Sent when a branch or tag is created, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#create
+type CreateEvent struct {
+	WebhookPayload
+	Ref          string `json:"ref"`
+	RefType      string `json:"ref_type"`
+	MasterBranch string `json:"master_branch"`
+	Description  string `json:"description"`
+	PusherType   string `json:"pusher_type"`
+}
+
+// Sent when a branch or tag is deleted, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#delete
+type DeleteEvent struct {
+	WebhookPayload
+	Ref        string `json:"ref"`
+	RefType    string `json:"ref_type"`
+	PusherType string `json:"pusher_type"`
+}
+
+// Sent when a repository is forked, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#fork
+type ForkEvent struct {
+	WebhookPayload
+	Forkee Repository `json:"forkee"`
+}
+
+// Sent when a repository is starred or unstarred, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#star
+type StarEvent struct {
+	WebhookPayload
+	StarredAt *time.Time `json:"starred_at"`
+}
+
+// A label of an issue or pull request.
+type Label struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// An issue, as it comes in webhook payloads.
+type Issue struct {
+	Id        int        `json:"id"`
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"`
+	Locked    bool       `json:"locked"`
+	User      User       `json:"user"`
+	Labels    []Label    `json:"labels"`
+	Assignees []User     `json:"assignees"`
+	Comments  int        `json:"comments"`
+	Url       string     `json:"url"`
+	HtmlUrl   string     `json:"html_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+
+	// Set when the issue is a pull request.
+	PullRequest *struct {
+		Url     string `json:"url"`
+		HtmlUrl string `json:"html_url"`
+	} `json:"pull_request"`
+}
+
+// Sent when an issue is opened, edited, closed, labelled and so on, as
+// described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#issues
+type IssuesEvent struct {
+	WebhookPayload
+	Issue Issue `json:"issue"`
+
+	// The label that was added or removed, or the user who was assigned or
+	// unassigned, for those actions.
+	Label    *Label `json:"label"`
+	Assignee *User  `json:"assignee"`
+}
+
+// A comment on an issue or pull request.
+type IssueComment struct {
+	Id        int       `json:"id"`
+	Body      string    `json:"body"`
+	User      User      `json:"user"`
+	Url       string    `json:"url"`
+	HtmlUrl   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Sent when a comment on an issue or pull request is created, edited or
+// deleted, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#issue_comment
+type IssueCommentEvent struct {
+	WebhookPayload
+	Issue   Issue        `json:"issue"`
+	Comment IssueComment `json:"comment"`
+}
+
+// The head or base of a pull request.
+type PullRequestBranch struct {
+	Label string      `json:"label"`
+	Ref   string      `json:"ref"`
+	Sha   string      `json:"sha"`
+	User  User        `json:"user"`
+	Repo  *Repository `json:"repo"`
+}
+
+// A pull request, as it comes in webhook payloads.
+type PullRequest struct {
+	Id             int               `json:"id"`
+	Number         int               `json:"number"`
+	Title          string            `json:"title"`
+	Body           string            `json:"body"`
+	State          string            `json:"state"`
+	Draft          bool              `json:"draft"`
+	Locked         bool              `json:"locked"`
+	User           User              `json:"user"`
+	Labels         []Label           `json:"labels"`
+	Assignees      []User            `json:"assignees"`
+	Head           PullRequestBranch `json:"head"`
+	Base           PullRequestBranch `json:"base"`
+	Merged         bool              `json:"merged"`
+	MergedBy       *User             `json:"merged_by"`
+	MergeCommitSha string            `json:"merge_commit_sha"`
+	Url            string            `json:"url"`
+	HtmlUrl        string            `json:"html_url"`
+	DiffUrl        string            `json:"diff_url"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	ClosedAt       *time.Time        `json:"closed_at"`
+	MergedAt       *time.Time        `json:"merged_at"`
+}
+
+// Sent when a pull request is opened, synchronized, closed and so on, as
+// described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#pull_request
+type PullRequestEvent struct {
+	WebhookPayload
+	Number      int         `json:"number"`
+	PullRequest PullRequest `json:"pull_request"`
+
+	// The commits before and after a "synchronize", and the label that was
+	// added or removed, for those actions.
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Label  *Label `json:"label"`
+}
+
+// A file attached to a release.
+type ReleaseAsset struct {
+	Id                 int       `json:"id"`
+	Name               string    `json:"name"`
+	Label              string    `json:"label"`
+	State              string    `json:"state"`
+	ContentType        string    `json:"content_type"`
+	Size               int       `json:"size"`
+	DownloadCount      int       `json:"download_count"`
+	BrowserDownloadUrl string    `json:"browser_download_url"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// A release of a repository.
+type Release struct {
+	Id              int            `json:"id"`
+	TagName         string         `json:"tag_name"`
+	TargetCommitish string         `json:"target_commitish"`
+	Name            string         `json:"name"`
+	Body            string         `json:"body"`
+	Draft           bool           `json:"draft"`
+	Prerelease      bool           `json:"prerelease"`
+	Author          User           `json:"author"`
+	Assets          []ReleaseAsset `json:"assets"`
+	Url             string         `json:"url"`
+	HtmlUrl         string         `json:"html_url"`
+	TarballUrl      string         `json:"tarball_url"`
+	ZipballUrl      string         `json:"zipball_url"`
+	CreatedAt       time.Time      `json:"created_at"`
+	PublishedAt     *time.Time     `json:"published_at"`
+}
+
+// Sent when a release is published, edited, deleted and so on, as described
+// here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#release
+type ReleaseEvent struct {
+	WebhookPayload
+	Release Release `json:"release"`
+}
+
+// A repository that a GitHub App was installed on, or removed from.
+type InstallationRepository struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Private  bool   `json:"private"`
+}
+
+// Sent when a GitHub App is installed, uninstalled, suspended and so on, as
+// described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#installation
+type InstallationEvent struct {
+	WebhookPayload
+	Repositories []InstallationRepository `json:"repositories"`
+}
+
+// Sent when repositories are added to, or removed from, an installation of a
+// GitHub App, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#installation_repositories
+type InstallationRepositoriesEvent struct {
+	WebhookPayload
+	RepositorySelection string                   `json:"repository_selection"`
+	RepositoriesAdded   []InstallationRepository `json:"repositories_added"`
+	RepositoriesRemoved []InstallationRepository `json:"repositories_removed"`
+}
+
+func (*PingEvent) EventName() string                     { return "ping" }
+func (*PushEvent) EventName() string                     { return "push" }
+func (*CreateEvent) EventName() string                   { return "create" }
+func (*DeleteEvent) EventName() string                   { return "delete" }
+func (*ForkEvent) EventName() string                     { return "fork" }
+func (*StarEvent) EventName() string                     { return "star" }
+func (*IssuesEvent) EventName() string                   { return "issues" }
+func (*IssueCommentEvent) EventName() string             { return "issue_comment" }
+func (*PullRequestEvent) EventName() string              { return "pull_request" }
+func (*ReleaseEvent) EventName() string                  { return "release" }
+func (*InstallationEvent) EventName() string             { return "installation" }
+func (*InstallationRepositoriesEvent) EventName() string { return "installation_repositories" }
+
+// Creates the struct for the payload of each event there is one for.
+var webhookEvents = map[string]func() WebhookEvent{
+	"ping":                      func() WebhookEvent { return new(PingEvent) },
+	"push":                      func() WebhookEvent { return new(PushEvent) },
+	"create":                    func() WebhookEvent { return new(CreateEvent) },
+	"delete":                    func() WebhookEvent { return new(DeleteEvent) },
+	"fork":                      func() WebhookEvent { return new(ForkEvent) },
+	"star":                      func() WebhookEvent { return new(StarEvent) },
+	"issues":                    func() WebhookEvent { return new(IssuesEvent) },
+	"issue_comment":             func() WebhookEvent { return new(IssueCommentEvent) },
+	"pull_request":              func() WebhookEvent { return new(PullRequestEvent) },
+	"release":                   func() WebhookEvent { return new(ReleaseEvent) },
+	"installation":              func() WebhookEvent { return new(InstallationEvent) },
+	"installation_repositories": func() WebhookEvent { return new(InstallationRepositoriesEvent) },
+}
@@ -0,0 +1,48 @@
+package gothub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPushEventTimestamps(t *testing.T) {
+	var e PushEvent
+	err := json.Unmarshal([]byte(`{
+		"ref": "refs/tags/v1.0.0",
+		"created": true,
+		"commits": [{"id": "6dcb09b5b57875f334f61aebed695e2e4193db5e", "timestamp": "2014-06-16T13:40:20-07:00", "added": ["README.md"]}],
+		"pusher": {"name": "octocat", "email": "octocat@github.com"},
+		"repository": {"full_name": "octocat/hello-world", "created_at": 1402950020, "pushed_at": 1402950020, "updated_at": "2014-06-16T20:20:20Z", "owner": {"login": "octocat"}},
+		"sender": {"login": "octocat"}
+	}`), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Ref != "refs/tags/v1.0.0" || !e.Created || len(e.Commits) != 1 || e.Commits[0].Added[0] != "README.md" || e.Pusher.Name != "octocat" || e.Sender.Login != "octocat" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	created := time.Unix(1402950020, 0)
+	if e.Repository == nil || !e.Repository.CreatedAt.Equal(created) || !e.Repository.PushedAt.Equal(created) || !e.Repository.UpdatedAt.Equal(created) || e.Repository.Owner.Login != "octocat" {
+		t.Errorf("Unexpected repository %+v", e.Repository)
+	}
+
+	// Deleting a branch leaves no head commit, and pushes with no repository
+	// leave it out.
+	e = PushEvent{}
+	if err := json.Unmarshal([]byte(`{"deleted": true, "head_commit": null}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Deleted || e.HeadCommit != nil || e.Repository != nil {
+		t.Errorf("Unexpected event %+v", e)
+	}
+}
+
+func TestWebhookEventNames(t *testing.T) {
+	for name, newEvent := range webhookEvents {
+		if e := newEvent(); e.EventName() != name {
+			t.Errorf("%T: expected %q, got %q", e, name, e.EventName())
+		}
+	}
+}
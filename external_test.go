@@ -0,0 +1,121 @@
+package gothub_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nesv/gothub"
+	"github.com/nesv/gothub/gothubtest"
+)
+
+// Starts a gothubtest server with octocat and hubot on it, for the tests that
+// only need the API to behave as usual, and returns it along with a session
+// for octocat, made with the given options as well.
+func newFakeGitHub(t *testing.T, opts ...gothub.Option) (*gothubtest.Server, *gothub.GitHub) {
+	t.Helper()
+	server := gothubtest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddUser(gothub.User{Login: "octocat", Name: "The Octocat"})
+	server.AddUser(gothub.User{Login: "hubot", Name: "Hubot"})
+	g, err := server.Session("octocat", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, g
+}
+
+func TestThrottleRate(t *testing.T) {
+	_, g := newFakeGitHub(t, gothub.WithThrottle(gothub.Throttle{RequestsPerSecond: 50, Burst: 2}))
+
+	// Two requests go out right away; the other four are spaced 20ms apart.
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, _, err := g.GetUser("octocat"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("Expected the requests to be spaced out, took %s", elapsed)
+	}
+	if stats := g.ThrottleStats(); stats.Delayed != 4 {
+		t.Errorf("Expected 4 requests to be delayed, got %+v", stats)
+	}
+}
+
+func TestThrottleCancel(t *testing.T) {
+	_, g := newFakeGitHub(t, gothub.WithThrottle(gothub.Throttle{RequestsPerSecond: 0.1}))
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.GetUserContext(ctx, "octocat"); err != context.DeadlineExceeded {
+		t.Errorf("Expected the wait to be cut short, got %v", err)
+	}
+	if stats := g.ThrottleStats(); stats.Waiting != 0 || stats.InFlight != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	if _, err := gothub.New(gothub.WithThrottle(gothub.Throttle{MaxConcurrent: -1})); err == nil {
+		t.Error("Expected a negative limit to be rejected")
+	}
+}
+
+func TestMemoryMetrics(t *testing.T) {
+	metrics := gothub.NewMemoryMetrics()
+	server, g := newFakeGitHub(t, gothub.WithMetrics(metrics))
+	server.SetRateLimit(60, 46, time.Time{})
+
+	for _, login := range []string{"octocat", "hubot", "nobody"} {
+		g.GetUser(login)
+	}
+	if _, _, err := g.AddPublicKey("laptop", "ssh-ed25519 AAAA1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if routes := metrics.Routes(); len(routes) != 2 || routes[0] != "GET /users/{user}" || routes[1] != "POST /user/keys" {
+		t.Errorf("Unexpected routes %q", routes)
+	}
+	if n := metrics.Requests("GET", "/users/{user}"); n != 3 {
+		t.Errorf("Expected 3 requests for users, got %d", n)
+	}
+	if n := metrics.Errors("GET", "/users/{user}", http.StatusNotFound); n != 1 {
+		t.Errorf("Expected 1 user not to be found, got %d", n)
+	}
+	if n := metrics.Errors("POST", "/user/keys", http.StatusNotFound); n != 0 {
+		t.Errorf("Expected no errors for the new key, got %d", n)
+	}
+
+	h := metrics.Latency("GET", "/users/{user}")
+	if h.Count != 3 || h.Sum <= 0 || len(h.Counts) != len(gothub.DefaultLatencyBuckets) || h.Counts[len(h.Counts)-1] != 3 {
+		t.Errorf("Unexpected latency histogram %+v", h)
+	}
+	if quota := metrics.Quota(); quota.Limit != 60 || quota.Remaining != 42 {
+		t.Errorf("Unexpected quota %+v", quota)
+	}
+	if n, _ := metrics.Throttled(); n != 0 {
+		t.Errorf("Expected no requests to be throttled, got %d", n)
+	}
+}
+
+func TestMetricsThrottleWait(t *testing.T) {
+	var waits []time.Duration
+	_, g := newFakeGitHub(t,
+		gothub.WithThrottle(gothub.Throttle{RequestsPerSecond: 100}),
+		gothub.WithMetrics(gothub.MetricsFunc(func(ctx context.Context, r *gothub.RequestMetrics) {
+			waits = append(waits, r.ThrottleWait)
+		})),
+	)
+	for i := 0; i < 2; i++ {
+		if _, _, err := g.GetUser("octocat"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(waits) != 2 || waits[0] != 0 || waits[1] <= 0 {
+		t.Errorf("Expected the second request to be held back, got %v", waits)
+	}
+}
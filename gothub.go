@@ -2,21 +2,29 @@ package gothub
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
-// The HTTP host that we will hit to use the GitHub API.
+// The HTTP hosts that we will hit to use the GitHub API, unless a session is
+// pointed elsewhere (see EnterpriseGuest and EnterpriseBasicLogin).
 const (
-	GitHubUrl       string = "https://api.github.com"
-	DefaultPageSize int    = 30
-	AcceptHeader    string = "application/vnd.github.beta+json"
+	GitHubUrl        string = "https://api.github.com"
+	GitHubUploadUrl  string = "https://uploads.github.com"
+	DefaultPageSize  int    = 30
+	AcceptHeader     string = "application/vnd.github.beta+json"
+	DefaultUserAgent string = "gothub"
 )
 
 var (
@@ -25,11 +33,71 @@ var (
 )
 
 // The GitHub struct represents an active session to the GitHub API.
+//
+// A session is safe for concurrent use by multiple goroutines, once it has
+// been set up. Every response updates its rate limit and OAuth scope fields,
+// so goroutines sharing a session should read those through Rate and
+// OAuthScopes, rather than directly.
 type GitHub struct {
-	httpClient         *http.Client
-	Authorization      string
+	httpClient    *http.Client
+	Authorization string
+
+	// The one-time password sent with every request, for accounts with
+	// two-factor authentication enabled; see WithOTP.
+	otp string
+
+	// Guards the rate limit and scope fields.
+	mu sync.RWMutex
+
 	RateLimit          int
 	RateLimitRemaining int
+	RateLimitUsed      int
+
+	// When the current rate limit window ends, and RateLimitRemaining goes
+	// back up to RateLimit. It is the zero time until the API has said.
+	RateLimitReset time.Time
+
+	// The root URLs of the REST API, and of the uploads API, that this
+	// session talks to. For GitHub Enterprise Server these look like
+	// "https://ghe.example.com/api/v3" and "https://ghe.example.com/api/uploads".
+	BaseUrl   string
+	UploadUrl string
+
+	// The value of the User-Agent header sent with every request; GitHub
+	// rejects requests without one.
+	UserAgent string
+
+	// The tokens the session spreads its requests across, in place of
+	// Authorization; see WithTokenPool.
+	pool *tokenPool
+
+	// Whether to wait for the rate limit to reset, rather than failing with
+	// ErrRateLimitReached; see WithRateLimitWait.
+	waitForRateLimit bool
+
+	// How failed requests are tried again, if at all; see WithRetryPolicy.
+	retryPolicy *RetryPolicy
+
+	// Holds back requests, if the session was given a Throttle; see
+	// WithThrottle.
+	throttle *throttle
+
+	// Told about every request the session sends; see WithTracer and
+	// WithMetrics.
+	tracer      Tracer
+	traceBodies bool
+	metrics     Metrics
+
+	// The OAuth scopes granted to the session's token, and the scopes that
+	// the most recently called endpoint accepts, as last reported by the
+	// X-OAuth-Scopes and X-Accepted-OAuth-Scopes headers. Both are nil for
+	// guest and basic authentication sessions.
+	Scopes         []string
+	AcceptedScopes []string
+
+	// The rate limit of the GraphQL API, which is kept apart from that of
+	// the REST API; see GraphQLRate.
+	graphqlRate Rate
 }
 
 func hashAuth(u, p string) string {
@@ -37,72 +105,133 @@ func hashAuth(u, p string) string {
 	return base64.StdEncoding.EncodeToString([]byte(a))
 }
 
-// Use API without authentication
-func Guest() (*GitHub, error) {
-	request, err := http.NewRequest("GET", GitHubUrl, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if response.StatusCode == http.StatusOK {
-		ratelimit, err := strconv.Atoi(response.Header.Get("X-RateLimit-Limit"))
-		if err != nil {
+// Creates a new session, configured by the given options, without making any
+// requests to the API.
+//
+// With no options, the session talks to api.github.com as a guest, using a
+// fresh http.Client. For example, to log in to an enterprise install with a
+// request timeout:
+//
+//	g, err := gothub.New(
+//	    gothub.WithBaseUrl("https://ghe.example.com/api/v3", ""),
+//	    gothub.WithBasicAuth("monalisa", "hunter2"),
+//	    gothub.WithHttpClient(&http.Client{Timeout: 30 * time.Second}),
+//	)
+func New(opts ...Option) (*GitHub, error) {
+	g := &GitHub{httpClient: &http.Client{}, UserAgent: DefaultUserAgent}
+	g.setUrls(GitHubUrl, GitHubUploadUrl)
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
 			return nil, err
 		}
-
-		remaining, err := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining"))
-		if err != nil {
-			return nil, err
-		}
-
-		return &GitHub{httpClient: client, RateLimit: ratelimit, RateLimitRemaining: remaining}, nil
 	}
+	return g, nil
+}
+
+// Use API without authentication
+func Guest() (*GitHub, error) {
+	return login()
+}
 
-	return nil, err
+// Use the API of a GitHub Enterprise Server install without authentication.
+//
+// The baseUrl is the root of the install's REST API, e.g.
+// "https://ghe.example.com/api/v3". If uploadUrl is empty, it is derived from
+// the baseUrl.
+func EnterpriseGuest(baseUrl, uploadUrl string) (*GitHub, error) {
+	return login(WithBaseUrl(baseUrl, uploadUrl))
 }
 
 // Log in to GitHub using basic, username/password authentication.
+//
+// If the account has two-factor authentication enabled, this fails with an
+// *OTPRequiredError; see BasicLoginOTP.
 func BasicLogin(username, password string) (*GitHub, error) {
-	// Format and Base64-encode the provided username and password, in preparation for basic
-	// HTTP auth.
-	authorization := fmt.Sprintf("Basic %s", hashAuth(username, password))
-	request, err := http.NewRequest("GET", GitHubUrl, nil)
+	return login(WithBasicAuth(username, password))
+}
+
+// Log in to GitHub using basic, username/password authentication, for an
+// account with two-factor authentication enabled. The one-time password is
+// sent with the login request, and every request after it.
+func BasicLoginOTP(username, password, otp string) (*GitHub, error) {
+	return login(WithBasicAuth(username, password), WithOTP(otp))
+}
+
+// Log in to GitHub using a personal access token, which is sent as
+// "Authorization: token <token>".
+//
+// To log in to a GitHub Enterprise Server install with a token, use New with
+// the WithBaseUrl and WithToken options.
+func TokenLogin(token string) (*GitHub, error) {
+	return login(WithToken(token))
+}
+
+// Log in to GitHub using an OAuth access token, which is sent as
+// "Authorization: Bearer <token>".
+func BearerLogin(token string) (*GitHub, error) {
+	return login(WithBearerToken(token))
+}
+
+// Log in to a GitHub Enterprise Server install using basic, username/password
+// authentication.
+//
+// See EnterpriseGuest for the meaning of baseUrl and uploadUrl.
+func EnterpriseBasicLogin(baseUrl, uploadUrl, username, password string) (*GitHub, error) {
+	return login(WithBaseUrl(baseUrl, uploadUrl), WithBasicAuth(username, password))
+}
+
+// Creates a new session with New, then makes sure it can actually talk to the
+// API (and, if it was given credentials, that they are accepted).
+func login(opts ...Option) (*GitHub, error) {
+	g, err := New(opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set the Authorization header.
-	request.Header.Set("Authorization", authorization)
+	request, err := g.newRequest(context.Background(), "GET", "/", nil)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create a new HTTP client (which we will eventually provide to the GitHub struct), for
-	// issuing the above HTTP request, and future requests.
-	client := &http.Client{}
-	response, err := client.Do(request)
+	// Issuing the request also fills in the session's rate limits, and
+	// fails if the credentials are not accepted.
+	response, err := g.call(request)
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 
-	if response.StatusCode == http.StatusOK {
-		// Yaaaaaaay!
-		ratelimit, err := strconv.Atoi(response.Header.Get("X-RateLimit-Limit"))
-		if err != nil {
-			return nil, err
-		}
+	if err := checkStatus(response, http.StatusOK); err != nil {
+		return nil, err
+	}
 
-		remaining, err := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining"))
-		if err != nil {
-			return nil, err
-		}
+	// Yaaaaaaay!
+	return g, nil
+}
+
+// Sets the session's API and uploads URLs, filling in the uploads URL of a
+// GitHub Enterprise Server install when it is not given.
+func (g *GitHub) setUrls(baseUrl, uploadUrl string) {
+	g.BaseUrl = strings.TrimRight(baseUrl, "/")
+	if uploadUrl == "" && strings.HasSuffix(g.BaseUrl, "/api/v3") {
+		uploadUrl = strings.TrimSuffix(g.BaseUrl, "/v3") + "/uploads"
+	}
+	g.UploadUrl = strings.TrimRight(uploadUrl, "/")
+}
 
-		return &GitHub{httpClient: client, Authorization: authorization,
-			RateLimit: ratelimit, RateLimitRemaining: remaining}, nil
+// Returns the absolute URL of the given API endpoint, relative to the
+// session's base URL. URLs that are already absolute (such as the ones in
+// pagination links) are returned as they are.
+func (g *GitHub) apiUrl(uri string) string {
+	if strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "http://") {
+		return uri
 	}
 
-	// Should we get here, the basic authentication request failed.
-	e := "Authorization failed with HTTP code: %d"
-	return nil, errors.New(fmt.Sprintf(e, response.StatusCode))
+	base := g.BaseUrl
+	if base == "" {
+		base = GitHubUrl
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(base, "/"), strings.TrimLeft(uri, "/"))
 }
 
 /*
@@ -112,118 +241,138 @@ unmarshals the JSON response (if there is one) into the provided interface{} v.
 You can use this function to interact with a majority of the GitHub v3
 endpoints.
 */
-func (g *GitHub) Do(v interface{}, method string, uriParts ...string) (err error) {
-	if g.RateLimitRemaining == 0 {
-		err = ErrRateLimitReached
-		return
-	}
+func (g *GitHub) Do(v interface{}, method string, uriParts ...string) (*Response, error) {
+	return g.DoContext(context.Background(), v, method, uriParts...)
+}
 
-	url := fmt.Sprintf("%s/%s", GitHubUrl, strings.Join(uriParts, "/"))
+// Like Do, but the request is bound to ctx, and is abandoned as soon as ctx is
+// cancelled or its deadline passes.
+func (g *GitHub) DoContext(ctx context.Context, v interface{}, method string, uriParts ...string) (*Response, error) {
+	return g.DoRequest(ctx, v, method, strings.Join(uriParts, "/"), nil, nil)
+}
 
-	var request *http.Request
-	request, err = http.NewRequest(method, url, nil)
-	if err != nil {
-		return
-	}
+/*
+Calls the specified GitHub endpoint, like DoContext, with the query parameters
+added to the URI and, unless it is nil, the body encoded as JSON:
 
-	request.Header.Set("Authorization", g.Authorization)
-	request.Header.Set("Accept", AcceptHeader)
+	var issue map[string]interface{}
+	body := map[string]string{"title": "Found a bug"}
+	_, err := g.DoRequest(ctx, &issue, "POST", "/repos/octocat/hello-world/issues", nil, body)
 
-	var response *http.Response
-	response, err = g.httpClient.Do(request)
+Any response without a 2xx status code is turned into an *ErrorResponse. When
+v is not nil, the JSON response (if there is one) is unmarshalled into it.
+*/
+func (g *GitHub) DoRequest(ctx context.Context, v interface{}, method, uri string, query url.Values, body interface{}) (resp *Response, err error) {
+	response, err := g.do(ctx, method, uri, query, body)
+	resp = newResponse(response)
 	if err != nil {
 		return
 	}
-	g.updateRates(response)
-
-	switch strings.ToUpper(method) {
-	case "GET":
-		switch response.StatusCode {
-		case http.StatusNoContent, http.StatusOK:
-			if response.ContentLength == 0 && v == nil {
-				// All is good in the hood.
-				return
-			}
-		default:
-			err = errors.New(fmt.Sprintf("GitHub API responded with HTTP %d", response.StatusCode))
-			return
-		}
-	}
+	defer response.Body.Close()
 
 	// If the user supplied an interface{} to unmarshal the JSON response
 	// body into, then do what is necessary!
-	if v != nil {
-		switch response.Header.Get("Content-Type") {
-		case "application/json":
-			fallthrough
-		case "application/json; charset=utf-8":
-			var body []byte
-			body, err = ioutil.ReadAll(response.Body)
-			if err != nil {
-				return
-			}
+	if v == nil || response.StatusCode == http.StatusNoContent {
+		return
+	}
+	var data []byte
+	if data, err = ioutil.ReadAll(response.Body); err != nil || len(data) == 0 {
+		// An empty body leaves v as it was.
+		return
+	}
+	if !isJSON(response.Header.Get("Content-Type")) {
+		err = ErrNoJSON
+		return
+	}
+	err = json.Unmarshal(data, v)
+	return
+}
 
-			err = json.Unmarshal(body, v)
-		default:
-			err = ErrNoJSON
+// Sends a request with the query parameters and JSON body of DoRequest, and
+// returns the response, as long as it has a 2xx status code. Its body is left
+// for the caller to read and close.
+func (g *GitHub) do(ctx context.Context, method, uri string, query url.Values, body interface{}) (*http.Response, error) {
+	var content io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
 		}
+		content = bytes.NewReader(b)
 	}
 
-	return
-}
+	request, err := g.newRequest(ctx, method, addQuery(uri, query), content)
+	if err != nil {
+		return nil, err
+	}
+	if content != nil {
+		request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
 
-// Updates the call limit rates in the GitHub struct.
-func (g *GitHub) updateRates(r *http.Response) (err error) {
-	limit, err := strconv.Atoi(r.Header.Get("X-RateLimit-Limit"))
+	response, err := g.call(request)
 	if err != nil {
-		return
+		return response, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response, newErrorResponse(response)
 	}
-	g.RateLimit = limit
+	return response, nil
+}
 
-	remaining, err := strconv.Atoi(r.Header.Get("X-RateLimit-Remaining"))
+// Reports whether the Content-Type is JSON, including the vendor-specific JSON
+// media types (such as "application/vnd.github+json").
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return
+		return false
 	}
-	g.RateLimitRemaining = remaining
-	return
+	return mediaType == "application/json" || (strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
 }
 
-// Calls the GitHub API and returns the raw, HTTP response body.
-func call(g *GitHub, method, uri string) (response *http.Response, err error) {
-	if g.RateLimitRemaining == 0 {
-		err = ErrRateLimitReached
-		return
+// Adds the query parameters to the uri, which may have some of its own.
+func addQuery(uri string, query url.Values) string {
+	if len(query) == 0 {
+		return uri
 	}
 
-	url := fmt.Sprintf("%s%s", GitHubUrl, uri)
-	request, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
 	}
+	return uri + sep + query.Encode()
+}
+
+// Updates the OAuth scopes in the GitHub struct, if the response reports them.
+func (g *GitHub) updateScopes(r *http.Response) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	if g.Authorization != "" {
-		// Use Authorization when you logged in
-		request.Header.Set("Authorization", g.Authorization)
+	if values := r.Header.Values("X-OAuth-Scopes"); len(values) > 0 {
+		g.Scopes = parseScopes(values[0])
 	}
-	request.Header.Set("Accept", AcceptHeader)
+	if values := r.Header.Values("X-Accepted-OAuth-Scopes"); len(values) > 0 {
+		g.AcceptedScopes = parseScopes(values[0])
+	}
+}
 
-	// Fire off the request.
-	response, err = g.httpClient.Do(request)
+// Calls the GitHub API and returns the raw, HTTP response body.
+func call(ctx context.Context, g *GitHub, method, uri string) (response *http.Response, err error) {
+	request, err := g.newRequest(ctx, method, uri, nil)
 	if err != nil {
 		return
 	}
 
-	// Update the call rates
-	g.updateRates(response)
+	// Fire off the request; this also updates the call rates.
+	response, err = g.call(request)
+	if err != nil {
+		return
+	}
 
 	// Check to make sure the API came back with an appropriate HTTP status
 	// code, depending on the request method
 	switch method {
 	case "GET":
-		if response.StatusCode != http.StatusOK {
-			e := "GitHub API responded with HTTP %d"
-			err = errors.New(fmt.Sprintf(e, response.StatusCode))
-		}
+		err = checkStatus(response, http.StatusOK)
 
 	case "POST":
 		switch response.StatusCode {
@@ -243,86 +392,152 @@ func call(g *GitHub, method, uri string) (response *http.Response, err error) {
 
 // Calls the GitHub API, but will unmarshal a JSON response to the struct
 // provided to `rs`.
-func (g *GitHub) callGithubApi(method, uri string, rs interface{}) error {
-	response, err := call(g, method, uri)
+//
+// The response is returned even alongside an error, if the API sent one.
+func (g *GitHub) callGithubApi(ctx context.Context, method, uri string, rs interface{}) (*Response, error) {
+	response, err := call(ctx, g, method, uri)
 	if err != nil {
-		return err
+		return newResponse(response), err
 	}
+	defer response.Body.Close()
 
 	// Check to make sure we actually got JSON back.
-	switch response.Header.Get("Content-Type") {
-	case "application/json":
-		fallthrough
-	case "application/json; charset=utf-8":
-		var js []byte
-		js, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			return err
-		} else {
-			err = json.Unmarshal(js, rs)
-		}
-	default:
-		err = ErrNoJSON
+	if !isJSON(response.Header.Get("Content-Type")) {
+		return newResponse(response), ErrNoJSON
 	}
 
-	return err
-}
-
-type unprocessableEntityError struct {
-	Resource string `json:"resource"`
-	Field    string `json:"field"`
-	Code     string `json:"code"`
-}
-
-type unprocessableEntity struct {
-	Message string                     `json:"message"`
-	Errors  []unprocessableEntityError `json:"errors"`
+	js, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return newResponse(response), err
+	}
+	return newResponse(response), json.Unmarshal(js, rs)
 }
 
 // Stuffs the approriate Authorization header into place on the request, then
 // calls the GitHub API and udpates the API limit rates.
+//
+// Should the session have been set up to, requests are held back until the
+// rate limit resets or the Throttle lets them through, and failed requests
+// are tried again (see RetryPolicy).
 func (g *GitHub) call(req *http.Request) (response *http.Response, err error) {
-	if g.RateLimitRemaining == 0 {
-		err = ErrRateLimitReached
-		return
-	}
+	retries := 0
+	for {
+		// The credentials, and the rate limit, that the request goes out
+		// with: the session's own, or those of a token from its pool.
+		s := g
+		if g.pool != nil {
+			s = g.pool.pick()
+		}
 
-	req.Header.Set("Authorization", g.Authorization)
+		if s.rateLimited() && !g.isGraphQL(req) {
+			if !g.waitForRateLimit {
+				err = ErrRateLimitReached
+				return
+			}
+			if err = s.waitForReset(req.Context()); err != nil {
+				return
+			}
+		}
 
-	response, err = g.httpClient.Do(req)
-	g.updateRates(response)
+		if s.Authorization != "" {
+			// Use Authorization when you logged in
+			req.Header.Set("Authorization", s.Authorization)
+		}
+		if otp := g.otpFor(req.Context()); otp != "" {
+			req.Header.Set(otpHeader, otp)
+		}
+		if g.UserAgent != "" {
+			req.Header.Set("User-Agent", g.UserAgent)
+		}
 
-	// Special handling for the HTTP 422 Unprocessable Entity.
-	if response.StatusCode != 422 {
-		return
-	}
+		var requestBody []byte
+		if g.tracer != nil && g.traceBodies {
+			requestBody = peekRequestBody(req)
+		}
 
-	// Stupid "err is shadowed during return"
-	body, err := ioutil.ReadAll(response.Body)
-	var uerror error
-	if err == nil {
-		var unprocessable unprocessableEntity
-		err = json.Unmarshal(body, &unprocessable)
-		if err != nil {
+		release, throttleWait, terr := g.throttle.acquire(req.Context(), isMutation(req))
+		if terr != nil {
+			return nil, terr
+		}
+		start := time.Now()
+		response, err = g.httpClient.Do(req)
+		latency := time.Since(start)
+		release()
+		if err == nil {
+			s.updateRates(response)
+			g.updateScopes(response)
+
+			// Any client or server error gets turned into an *ErrorResponse;
+			// the response is still handed back, for callers that expect some
+			// of them.
+			if response.StatusCode >= 400 {
+				err = otpError(response, newErrorResponse(response))
+			}
+		}
+		if g.tracer != nil {
+			g.trace(req, requestBody, response, err, latency, retries+1)
+		}
+		if g.metrics != nil {
+			g.observe(req, response, err, latency, throttleWait, retries+1)
+		}
+		if err == nil {
 			return
 		}
 
-		e := "%s: %+v"
-		uerror = errors.New(fmt.Sprintf(e, unprocessable.Message,
-			unprocessable.Errors))
-	}
+		// Whether we wait for the rate limit or retry, the request will have
+		// to be sent again, body and all.
+		rewound, rerr := rewindBody(req)
+		if rerr != nil {
+			return
+		}
+
+		var wait time.Duration
+		switch {
+		case g.pool != nil && errors.Is(err, ErrRateLimitReached) && s.rateLimited() && g.pool.available():
+			// The token is parked until its window resets, and another one
+			// takes over right away.
+		case g.waitForRateLimit && errors.Is(err, ErrRateLimitReached) && s.rateLimited():
+			// The API turned us away because we ran out of calls; we wait
+			// for the reset at the top of the loop.
+		case g.retryPolicy != nil:
+			var ok bool
+			if wait, ok = g.retryPolicy.backoff(req, response, err, retries); !ok {
+				return
+			}
+			retries++
+			if g.retryPolicy.OnRetry != nil {
+				g.retryPolicy.OnRetry(RetryEvent{Request: req, Response: response, Err: err, Attempt: retries, Wait: wait})
+			}
+		default:
+			return
+		}
 
-	if uerror != nil {
-		err = uerror
+		if response != nil {
+			response.Body.Close()
+		}
+		if serr := sleep(req.Context(), wait); serr != nil {
+			return nil, serr
+		}
+		req = rewound
 	}
+}
 
-	return
+// Builds a request for the given API endpoint, relative to the session's base
+// URL and bound to ctx, asking for the API's JSON media type (or the ones ctx
+// was given by WithMediaType). The Authorization and User-Agent headers are
+// filled in by (*GitHub).call.
+func (g *GitHub) newRequest(ctx context.Context, method, uri string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, g.apiUrl(uri), body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", acceptHeader(ctx))
+	return request, nil
 }
 
 // Makes an HTTP GET request to the specified GitHub endpoint.
-func (g *GitHub) httpGet(uri string, extraHeaders map[string]string) (resp *http.Response, err error) {
-	url := fmt.Sprintf("%s%s", GitHubUrl, uri)
-	request, err := http.NewRequest("GET", url, nil)
+func (g *GitHub) httpGet(ctx context.Context, uri string, extraHeaders map[string]string) (resp *http.Response, err error) {
+	request, err := g.newRequest(ctx, "GET", uri, nil)
 	if err != nil {
 		return
 	}
@@ -338,9 +553,8 @@ func (g *GitHub) httpGet(uri string, extraHeaders map[string]string) (resp *http
 }
 
 // Makes an HTTP POST request to the specified GitHub endpoint.
-func (g *GitHub) httpPost(uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
-	url := fmt.Sprintf("%s%s", GitHubUrl, uri)
-	request, err := http.NewRequest("POST", url, content)
+func (g *GitHub) httpPost(ctx context.Context, uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
+	request, err := g.newRequest(ctx, "POST", uri, content)
 	if err != nil {
 		return
 	}
@@ -359,13 +573,12 @@ func (g *GitHub) httpPost(uri string, extraHeaders map[string]string, content *b
 }
 
 // Makes an HTTP DELETE request to the specified GitHub endpoint.
-func (g *GitHub) httpDelete(uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
-	url := fmt.Sprintf("%s%s", GitHubUrl, uri)
+func (g *GitHub) httpDelete(ctx context.Context, uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
 	var request *http.Request
 	if content != nil {
-		request, err = http.NewRequest("DELETE", url, content)
+		request, err = g.newRequest(ctx, "DELETE", uri, content)
 	} else {
-		request, err = http.NewRequest("DELETE", url, nil)
+		request, err = g.newRequest(ctx, "DELETE", uri, nil)
 	}
 	if err != nil {
 		return
@@ -383,13 +596,12 @@ func (g *GitHub) httpDelete(uri string, extraHeaders map[string]string, content
 }
 
 // Makes an HTTP PUT request.
-func (g *GitHub) httpPut(uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
-	url := fmt.Sprintf("%s%s", GitHubUrl, uri)
+func (g *GitHub) httpPut(ctx context.Context, uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
 	var request *http.Request
 	if content != nil {
-		request, err = http.NewRequest("PUT", url, content)
+		request, err = g.newRequest(ctx, "PUT", uri, content)
 	} else {
-		request, err = http.NewRequest("PUT", url, nil)
+		request, err = g.newRequest(ctx, "PUT", uri, nil)
 	}
 	if err != nil {
 		return
@@ -407,13 +619,12 @@ func (g *GitHub) httpPut(uri string, extraHeaders map[string]string, content *by
 }
 
 // Makes an HTTP PATCH request.
-func (g *GitHub) httpPatch(uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
-	url := fmt.Sprintf("%s%s", GitHubUrl, uri)
+func (g *GitHub) httpPatch(ctx context.Context, uri string, extraHeaders map[string]string, content *bytes.Buffer) (resp *http.Response, err error) {
 	var request *http.Request
 	if content != nil {
-		request, err = http.NewRequest("PATCH", url, content)
+		request, err = g.newRequest(ctx, "PATCH", uri, content)
 	} else {
-		request, err = http.NewRequest("PATCH", url, nil)
+		request, err = g.newRequest(ctx, "PATCH", uri, nil)
 	}
 
 	if err != nil {
@@ -1,11 +1,30 @@
 package gothub
 
 import (
+	"context"
 	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/nesv/gothub/replay"
 )
 
+// Records new fixtures for the tests that talk to the API, rather than playing
+// back the ones in testdata/replay:
+//
+//	GITHUB_USERNAME=... GITHUB_PASSWORD=... go test -record -run TestGetUser
+var record = flag.Bool("record", false, "record the API fixtures in testdata/replay against the live API")
+
 func getTestingCredentials() (username, password string, err error) {
 	username = os.Getenv("GITHUB_USERNAME")
 	password = os.Getenv("GITHUB_PASSWORD")
@@ -23,8 +42,47 @@ func getTestingCredentials() (username, password string, err error) {
 	return
 }
 
+// Returns the options for a session that plays back the test's fixture from
+// testdata/replay, or that records it anew against the live API when the
+// tests are run with -record.
+func replayOptions(t *testing.T) []Option {
+	mode := replay.ModeReplay
+	username, password := "gothub-tester", replay.Redacted
+	if *record {
+		var err error
+		if username, password, err = getTestingCredentials(); err != nil {
+			t.Fatal(err)
+		}
+		mode = replay.ModeRecord
+	}
+
+	path := filepath.Join("testdata", "replay", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+	transport, err := replay.New(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.Secrets = []string{password}
+	t.Cleanup(func() {
+		if err := transport.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return []Option{WithTransport(transport), WithBasicAuth(username, password)}
+}
+
+// Returns a session that plays back the test's fixture; see replayOptions.
+func replaySession(t *testing.T) *GitHub {
+	g, err := New(replayOptions(t)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
 func TestGuest(t *testing.T) {
-	if g, err := Guest(); err != nil {
+	// Only the transport is wanted; a guest has no credentials.
+	if g, err := login(replayOptions(t)[0]); err != nil {
 		t.Fatal(err)
 	} else {
 		t.Logf("RateLimit-Limit: %d", g.RateLimit)
@@ -33,17 +91,320 @@ func TestGuest(t *testing.T) {
 }
 
 func TestBasicAuth(t *testing.T) {
-	username, password, err := getTestingCredentials()
+	if g, err := login(replayOptions(t)...); err != nil {
+		t.Fatal(err)
+	} else {
+		t.Logf("RateLimit-Limit: %d", g.RateLimit)
+		t.Logf("RateLimit-Remaining: %d", g.RateLimitRemaining)
+	}
+}
+
+// Starts a fake GitHub that answers every request with handler, and returns a
+// session pointed at it, made with the given options as well, along with the
+// server, which is closed once the test is over.
+func newTestSession(t *testing.T, handler http.HandlerFunc, opts ...Option) (*GitHub, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	g, err := New(append([]Option{WithBaseUrl(server.URL, "")}, opts...)...)
 	if err != nil {
 		t.Fatal(err)
 	}
+	return g, server
+}
 
-	var g *GitHub
-	if g, err = BasicLogin(username, password); err != nil {
+// Starts an HTTP server that stands in for a GitHub Enterprise Server install,
+// serving its API from under "/api/v3". The server is closed once the test is
+// over.
+func newEnterpriseServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/v3/") {
+			t.Errorf("Request for %s is outside of the enterprise API root", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEnterpriseGuest(t *testing.T) {
+	server := newEnterpriseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/":
+			w.WriteHeader(http.StatusOK)
+		case "/api/v3/users/octocat":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `{"login": "octocat", "id": 1}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	g, err := EnterpriseGuest(server.URL+"/api/v3/", "")
+	if err != nil {
 		t.Fatal(err)
-	} else {
-		t.Logf("Authorization: %s", g.Authorization)
-		t.Logf("RateLimit-Limit: %d", g.RateLimit)
-		t.Logf("RateLimit-Remaining: %d", g.RateLimitRemaining)
+	}
+
+	if g.BaseUrl != server.URL+"/api/v3" {
+		t.Errorf("BaseUrl is %q", g.BaseUrl)
+	}
+	if g.UploadUrl != server.URL+"/api/uploads" {
+		t.Errorf("UploadUrl is %q", g.UploadUrl)
+	}
+	if g.RateLimit != 5000 || g.RateLimitRemaining != 4999 {
+		t.Errorf("Rates were not read from the enterprise host: %d/%d", g.RateLimitRemaining, g.RateLimit)
+	}
+
+	var user User
+	if _, err := g.Do(&user, "GET", "users", "octocat"); err != nil {
+		t.Fatal(err)
+	} else if user.Login != "octocat" {
+		t.Errorf("Expected octocat, got %q", user.Login)
+	}
+}
+
+func TestEnterpriseBasicLogin(t *testing.T) {
+	authorization := "Basic " + hashAuth("monalisa", "hunter2")
+	server := newEnterpriseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != authorization {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v3/":
+			w.WriteHeader(http.StatusOK)
+		case "/api/v3/user":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprint(w, `{"login": "monalisa", "id": 2}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	if _, err := EnterpriseBasicLogin(server.URL+"/api/v3", "", "monalisa", "wrong"); err == nil {
+		t.Error("Logging in with a bad password should fail")
+	}
+
+	g, err := EnterpriseBasicLogin(server.URL+"/api/v3", server.URL+"/uploads", "monalisa", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.UploadUrl != server.URL+"/uploads" {
+		t.Errorf("UploadUrl is %q", g.UploadUrl)
+	}
+
+	user, _, err := g.GetCurrentUser()
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Login != "monalisa" {
+		t.Errorf("Expected monalisa, got %q", user.Login)
+	}
+}
+
+func TestDoContextCancel(t *testing.T) {
+	started := make(chan struct{})
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	var user User
+	_, err := g.DoContext(ctx, &user, "GET", "users", "octocat")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the request to be cancelled, got %v", err)
+	}
+}
+
+func TestGetUserContextDeadline(t *testing.T) {
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+			t.Error("The request outlived its deadline")
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := g.GetUserContext(ctx, "octocat"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+// Starts a fake GitHub that serves every endpoint the session tests below
+// call, counting down the rate limit with each request, and returns a session
+// pointed at it, made with the given options as well.
+func newRateCountingServer(t *testing.T, opts ...Option) (*GitHub, *int64) {
+	var requests int64
+	var mu sync.Mutex
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		used := requests
+		mu.Unlock()
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(5000-used))
+		w.Header().Set("X-RateLimit-Used", fmt.Sprint(used))
+		w.Header().Set("X-OAuth-Scopes", "repo, user")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/user/following/"):
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/user/keys", strings.HasSuffix(r.URL.Path, "/repos"):
+			fmt.Fprint(w, `[{"id": 1}]`)
+		case strings.HasPrefix(r.URL.Path, "/user/keys/"):
+			fmt.Fprint(w, `{"id": 1}`)
+		case strings.HasPrefix(r.URL.Path, "/users/"):
+			fmt.Fprint(w, `{"login": "octocat"}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, opts...)
+	return g, &requests
+}
+
+func TestSessionMethodsUpdateRates(t *testing.T) {
+	g, _ := newRateCountingServer(t, WithToken("secret"))
+
+	// These used to have value receivers, so the rates they read were lost
+	// along with the copy of the session.
+	calls := []func() error{
+		func() error { _, _, err := g.IsFollowing("octocat"); return err },
+		func() error { _, err := g.Follow("octocat"); return err },
+		func() error { _, err := g.Unfollow("octocat"); return err },
+		func() error { _, _, err := g.PublicKeys(); return err },
+		func() error { _, _, err := g.GetPublicKey(1); return err },
+		func() error { _, _, err := g.Repositories(); return err },
+	}
+	for i, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("Call #%d: %s", i+1, err)
+		}
+		if used := g.Rate().Used; used != i+1 {
+			t.Errorf("Call #%d: expected %d calls used, got %d", i+1, i+1, used)
+		}
+	}
+}
+
+func TestSessionConcurrentUse(t *testing.T) {
+	g, requests := newRateCountingServer(t, WithToken("secret"))
+
+	const workers, rounds = 8, 10
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				user, _, err := g.GetUser("octocat")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, _, err := user.Repositories(); err != nil {
+					t.Error(err)
+				}
+				if _, err := g.Follow("octocat"); err != nil {
+					t.Error(err)
+				}
+				if !g.HasScope("public_repo") {
+					t.Error("Expected the session to have the public_repo scope")
+				}
+				g.Rate()
+			}
+		}()
+	}
+	wg.Wait()
+
+	rate := g.Rate()
+	if rate.Limit != 5000 || rate.Used < 1 || rate.Used > int(*requests) {
+		t.Errorf("Unexpected rate %+v after %d requests", rate, *requests)
+	}
+	if *requests != workers*rounds*3 {
+		t.Errorf("Expected %d requests, got %d", workers*rounds*3, *requests)
+	}
+}
+
+func TestDoRequestBodyAndQuery(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method != "POST" || r.URL.Path != "/repos/octocat/hello-world/issues" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		if q := r.URL.Query(); q.Get("state") != "open" || q.Get("page") != "2" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("Unexpected Content-Type %q", ct)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != `{"title":"Found a bug"}` {
+			t.Errorf("Unexpected body %s", body)
+		}
+
+		response := jsonResponse(r, http.StatusCreated, `{"number": 1347}`)
+		response.Header.Set("Content-Type", "application/vnd.github+json")
+		return response, nil
+	})
+	g, err := New(WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issue struct {
+		Number int `json:"number"`
+	}
+	query := url.Values{"state": {"open"}}
+	body := map[string]string{"title": "Found a bug"}
+	resp, err := g.DoRequest(context.Background(), &issue, "POST", "/repos/octocat/hello-world/issues?page=2", query, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Number != 1347 || resp.StatusCode != http.StatusCreated {
+		t.Errorf("Unexpected issue %+v (HTTP %d)", issue, resp.StatusCode)
+	}
+}
+
+func TestDoRequestChecksStatus(t *testing.T) {
+	status := http.StatusFound
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(r, status, `{"message": "Nope"}`), nil
+	})
+	g, err := New(WithHttpClient(&http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{"GET", "POST", "PATCH", "PUT", "DELETE"} {
+		_, err := g.DoRequest(context.Background(), nil, method, "/user", nil, nil)
+		var e *ErrorResponse
+		if !errors.As(err, &e) || e.Response.StatusCode != http.StatusFound {
+			t.Errorf("%s: expected an *ErrorResponse for an HTTP 302, got %v", method, err)
+		}
+	}
+
+	status = http.StatusNoContent
+	var v map[string]interface{}
+	if _, err := g.DoRequest(context.Background(), &v, "DELETE", "/user/keys/1", nil, nil); err != nil {
+		t.Errorf("Unexpected error for an HTTP 204: %s", err)
 	}
 }
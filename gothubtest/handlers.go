@@ -0,0 +1,845 @@
+package gothubtest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nesv/gothub"
+)
+
+type contextKey int
+
+const (
+	// Carries the login of the authenticated user of a request.
+	loginKey contextKey = iota
+
+	// Carries the values of the wildcards in the route a request matched.
+	pathValuesKey
+)
+
+// A route of the server: the method and the path of the requests it serves.
+// Each segment of the path is either matched as it is, or a wildcard, which
+// is "{name}" for a single segment, or "{name...}" for the rest of the path.
+type route struct {
+	method   string
+	segments []string
+	handler  http.HandlerFunc
+}
+
+// The routes of the server. Requests are matched against them by hand,
+// rather than by http.ServeMux's patterns, which need Go 1.22 and a module
+// that asks for it.
+type router []route
+
+func (rt *router) handle(method, path string, handler http.HandlerFunc) {
+	*rt = append(*rt, route{method, strings.Split(strings.Trim(path, "/"), "/"), handler})
+}
+
+func (rt router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for _, route := range rt {
+		if route.method != r.Method {
+			continue
+		}
+		if values, ok := route.match(segments); ok {
+			route.handler(w, r.WithContext(context.WithValue(r.Context(), pathValuesKey, values)))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "")
+}
+
+// Returns the values of the route's wildcards, if the segments of a
+// request's path match it.
+func (rt route) match(segments []string) (values map[string]string, ok bool) {
+	values = make(map[string]string)
+	for i, segment := range rt.segments {
+		if !strings.HasPrefix(segment, "{") {
+			if i >= len(segments) || segments[i] != segment {
+				return nil, false
+			}
+			continue
+		}
+
+		name := segment[1 : len(segment)-1]
+		if strings.HasSuffix(name, "...") {
+			var rest string
+			if i < len(segments) {
+				rest = strings.Join(segments[i:], "/")
+			}
+			value, err := url.PathUnescape(rest)
+			if err != nil {
+				return nil, false
+			}
+			values[strings.TrimSuffix(name, "...")] = value
+			return values, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		value, err := url.PathUnescape(segments[i])
+		if err != nil || value == "" {
+			return nil, false
+		}
+		values[name] = value
+	}
+	return values, len(segments) == len(rt.segments)
+}
+
+// Returns the value of the named wildcard in the route the request matched.
+func pathValue(r *http.Request, name string) string {
+	values, _ := r.Context().Value(pathValuesKey).(map[string]string)
+	return values[name]
+}
+
+func (s *Server) routes() http.Handler {
+	var rt router
+	rt.handle("GET", "/", s.root)
+
+	rt.handle("GET", "/user", s.currentUser)
+	rt.handle("GET", "/users/{login}", s.getUser)
+
+	rt.handle("GET", "/user/emails", s.emails)
+	rt.handle("POST", "/user/emails", s.addEmails)
+	rt.handle("DELETE", "/user/emails", s.deleteEmails)
+
+	rt.handle("GET", "/users/{login}/followers", s.followers)
+	rt.handle("GET", "/users/{login}/following", s.following)
+	rt.handle("GET", "/user/following/{login}", s.isFollowing)
+	rt.handle("PUT", "/user/following/{login}", s.followUser)
+	rt.handle("DELETE", "/user/following/{login}", s.unfollowUser)
+
+	rt.handle("GET", "/users/{login}/keys", s.userKeys)
+	rt.handle("GET", "/user/keys", s.keys)
+	rt.handle("POST", "/user/keys", s.createKey)
+	rt.handle("GET", "/user/keys/{id}", s.getKey)
+	rt.handle("DELETE", "/user/keys/{id}", s.deleteKey)
+
+	rt.handle("GET", "/user/orgs", s.orgsOfCurrentUser)
+	rt.handle("GET", "/users/{login}/orgs", s.orgsOfUser)
+	rt.handle("GET", "/orgs/{org}", s.getOrg)
+
+	rt.handle("GET", "/user/repos", s.reposOfCurrentUser)
+	rt.handle("GET", "/users/{login}/repos", s.reposOfUser)
+
+	rt.handle("GET", "/legacy/issues/search/{rest...}", s.searchIssues)
+	rt.handle("GET", "/legacy/repos/search/{keyword}/{rest...}", s.searchRepositories)
+	rt.handle("GET", "/legacy/repos/search/{keyword}", s.searchRepositories)
+	rt.handle("GET", "/legacy/user/search/{keyword}/{rest...}", s.searchUsers)
+	rt.handle("GET", "/legacy/user/search/{keyword}", s.searchUsers)
+	rt.handle("GET", "/legacy/user/email/{email}", s.searchEmail)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serve(rt, w, r)
+	})
+}
+
+// Does what the API does for every request, before handing it to the route
+// it was made to: fail it if a fault says so, count it against the rate
+// limit, and authenticate it.
+func (s *Server) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+
+	s.mu.Lock()
+	s.requests++
+	h.Set("X-GitHub-Request-Id", fmt.Sprintf("GOTHUBTEST:%04X", s.requests))
+	fault := s.fault(r)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		for name, values := range fault.Header {
+			h[name] = values
+		}
+		status := fault.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		writeError(w, status, fault.Message)
+		return
+	}
+
+	if !s.countCall(h) {
+		writeError(w, http.StatusForbidden, "API rate limit exceeded")
+		return
+	}
+
+	login, scoped, ok := s.authenticate(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+	if scoped {
+		h.Set("X-OAuth-Scopes", Scopes)
+	}
+	if login != "" {
+		r = r.WithContext(context.WithValue(r.Context(), loginKey, login))
+	}
+	next.ServeHTTP(w, r)
+}
+
+// Returns the fault the request matches, if any. Expects s.mu to be held.
+func (s *Server) fault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// Counts a call against the rate limit, and sets the rate limit headers.
+// Returns false if the limit has already been reached.
+func (s *Server) countCall(h http.Header) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.rateReset.IsZero() || !now.Before(s.rateReset) {
+		s.rateRemaining = s.rateLimit
+		s.rateReset = now.Add(time.Hour).Truncate(time.Second)
+	}
+
+	ok := s.rateRemaining > 0
+	if ok {
+		s.rateRemaining--
+	}
+	h.Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(s.rateRemaining))
+	h.Set("X-RateLimit-Used", strconv.Itoa(s.rateLimit-s.rateRemaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(s.rateReset.Unix(), 10))
+	h.Set("X-RateLimit-Resource", "core")
+	return ok
+}
+
+// Works out who the request was made by, from its Authorization header. The
+// login is empty for requests made without credentials; scoped is true for
+// requests made with a token.
+func (s *Server) authenticate(r *http.Request) (login string, scoped, ok bool) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return "", false, true
+	}
+
+	scheme, credentials, _ := strings.Cut(auth, " ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToLower(scheme) {
+	case "token", "bearer":
+		login, ok = s.tokens[credentials]
+		return login, ok, ok
+	case "basic":
+		b, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return "", false, false
+		}
+		username, password, _ := strings.Cut(string(b), ":")
+		if a, exists := s.accounts[username]; exists && a.password != "" && a.password == password {
+			return username, false, true
+		}
+	}
+	return "", false, false
+}
+
+// Returns the account of the authenticated user. Unauthenticated requests are
+// answered with an HTTP 401, as are requests for a user who has since been
+// replaced; either way, the caller should then give up on the request.
+// Expects s.mu to be held.
+func (s *Server) current(w http.ResponseWriter, r *http.Request) *account {
+	login, _ := r.Context().Value(loginKey).(string)
+	a, ok := s.accounts[login]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Requires authentication")
+		return nil
+	}
+	return a
+}
+
+// Returns the account of the user named in the request's path, answering
+// with an HTTP 404 if there is none. Expects s.mu to be held.
+func (s *Server) named(w http.ResponseWriter, r *http.Request) *account {
+	a, ok := s.accounts[pathValue(r, "login")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "")
+		return nil
+	}
+	return a
+}
+
+func (s *Server) root(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"current_user_url": s.URL + "/user",
+		"user_url":         s.URL + "/users/{user}",
+	})
+}
+
+// Fills in the counts of the user's followers, the users they follow and
+// their repositories. Expects s.mu to be held.
+func (s *Server) user(a *account) gothub.User {
+	u := a.user
+	u.Following = len(a.following)
+	u.PublicRepos = 0
+	for _, repo := range a.repos {
+		if !repo.Private {
+			u.PublicRepos++
+		}
+	}
+	u.Followers = len(s.followersOf(a.user.Login))
+	return u
+}
+
+func (s *Server) followersOf(login string) (followers []gothub.Follower) {
+	for _, l := range s.logins {
+		for _, f := range s.accounts[l].following {
+			if f == login {
+				followers = append(followers, s.follower(s.accounts[l].user))
+				break
+			}
+		}
+	}
+	return
+}
+
+func (s *Server) currentUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.current(w, r); a != nil {
+		writeJSON(w, http.StatusOK, s.user(a))
+	}
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.named(w, r); a != nil {
+		writeJSON(w, http.StatusOK, s.user(a))
+	}
+}
+
+func (s *Server) emails(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.current(w, r); a != nil {
+		writeJSON(w, http.StatusOK, append([]string{}, a.emails...))
+	}
+}
+
+func (s *Server) addEmails(w http.ResponseWriter, r *http.Request) {
+	emails, ok := readEmails(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	for _, email := range emails {
+		if !contains(a.emails, email) {
+			a.emails = append(a.emails, email)
+		}
+	}
+	writeJSON(w, http.StatusCreated, emails)
+}
+
+func (s *Server) deleteEmails(w http.ResponseWriter, r *http.Request) {
+	emails, ok := readEmails(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	kept := a.emails[:0]
+	for _, email := range a.emails {
+		if !contains(emails, email) {
+			kept = append(kept, email)
+		}
+	}
+	a.emails = kept
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reads the email addresses in the body of a request, which must be a JSON
+// array of them.
+func readEmails(w http.ResponseWriter, r *http.Request) (emails []string, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&emails); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return nil, false
+	}
+	if len(emails) == 0 {
+		writeValidation(w, "Email", "email", "missing_field")
+		return nil, false
+	}
+	return emails, true
+}
+
+func (s *Server) followers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.named(w, r); a != nil {
+		s.writePage(w, r, s.followersOf(a.user.Login))
+	}
+}
+
+func (s *Server) following(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.named(w, r)
+	if a == nil {
+		return
+	}
+	var following []gothub.Follower
+	for _, login := range a.following {
+		if f, ok := s.accounts[login]; ok {
+			following = append(following, s.follower(f.user))
+		}
+	}
+	s.writePage(w, r, following)
+}
+
+func (s *Server) isFollowing(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	if contains(a.following, pathValue(r, "login")) {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		writeError(w, http.StatusNotFound, "")
+	}
+}
+
+func (s *Server) followUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	if !s.follow(a.user.Login, pathValue(r, "login")) {
+		writeError(w, http.StatusNotFound, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	kept := a.following[:0]
+	for _, login := range a.following {
+		if login != pathValue(r, "login") {
+			kept = append(kept, login)
+		}
+	}
+	a.following = kept
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) userKeys(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.named(w, r)
+	if a == nil {
+		return
+	}
+	// Other users only get to see the keys themselves.
+	keys := make([]gothub.PublicKey, len(a.keys))
+	for i, key := range a.keys {
+		keys[i] = gothub.PublicKey{Id: key.Id, Key: key.Key}
+	}
+	s.writePage(w, r, keys)
+}
+
+func (s *Server) keys(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.current(w, r); a != nil {
+		s.writePage(w, r, a.keys)
+	}
+}
+
+func (s *Server) createKey(w http.ResponseWriter, r *http.Request) {
+	var key gothub.PublicKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if key.Key == "" {
+		writeValidation(w, "PublicKey", "key", "missing_field")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	for _, l := range s.logins {
+		for _, k := range s.accounts[l].keys {
+			if k.Key == key.Key {
+				writeError(w, http.StatusUnprocessableEntity, "Validation Failed",
+					gothub.Error{Resource: "PublicKey", Field: "key", Code: "custom", Message: "key is already in use"})
+				return
+			}
+		}
+	}
+
+	key = s.addKey(a, gothub.PublicKey{Title: key.Title, Key: key.Key})
+	w.Header().Set("Location", key.Url)
+	writeJSON(w, http.StatusCreated, key)
+}
+
+// Returns the index of the authenticated user's key with the ID in the
+// request's path, answering with an HTTP 404 if there is none. Expects s.mu
+// to be held.
+func (s *Server) key(w http.ResponseWriter, r *http.Request, a *account) int {
+	id, _ := strconv.Atoi(pathValue(r, "id"))
+	for i, key := range a.keys {
+		if key.Id == id {
+			return i
+		}
+	}
+	writeError(w, http.StatusNotFound, "")
+	return -1
+}
+
+func (s *Server) getKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	if i := s.key(w, r, a); i >= 0 {
+		writeJSON(w, http.StatusOK, a.keys[i])
+	}
+}
+
+func (s *Server) deleteKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.current(w, r)
+	if a == nil {
+		return
+	}
+	if i := s.key(w, r, a); i >= 0 {
+		a.keys = append(a.keys[:i:i], a.keys[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) orgsOf(a *account) []gothub.Organization {
+	var orgs []gothub.Organization
+	for _, name := range a.orgs {
+		if org, ok := s.orgs[name]; ok {
+			orgs = append(orgs, *org)
+		}
+	}
+	return orgs
+}
+
+func (s *Server) orgsOfCurrentUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.current(w, r); a != nil {
+		s.writePage(w, r, s.orgsOf(a))
+	}
+}
+
+func (s *Server) orgsOfUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.named(w, r); a != nil {
+		s.writePage(w, r, s.orgsOf(a))
+	}
+}
+
+func (s *Server) getOrg(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org, ok := s.orgs[pathValue(r, "org")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, org)
+}
+
+func (s *Server) reposOfCurrentUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.current(w, r); a != nil {
+		s.writePage(w, r, a.repos)
+	}
+}
+
+func (s *Server) reposOfUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.named(w, r)
+	if a == nil {
+		return
+	}
+	login, _ := r.Context().Value(loginKey).(string)
+	var repos []gothub.Repository
+	for _, repo := range a.repos {
+		if !repo.Private || login == a.user.Login {
+			repos = append(repos, repo)
+		}
+	}
+	s.writePage(w, r, repos)
+}
+
+// The server doesn't keep any issues, so there are never any to find.
+func (s *Server) searchIssues(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]gothub.IssuesSearchResult{"issues": {}})
+}
+
+// Finds the public repositories whose name or description contain the
+// keyword. The language, if given, has to match too; the start page and
+// ordering are ignored.
+func (s *Server) searchRepositories(w http.ResponseWriter, r *http.Request) {
+	keyword := pathKeyword(r)
+	language, _, _ := strings.Cut(pathValue(r, "rest"), "/")
+	if _, err := strconv.Atoi(language); err == nil {
+		language = ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := []gothub.RepositoriesSearchResult{}
+	for _, l := range s.logins {
+		for _, repo := range s.accounts[l].repos {
+			if repo.Private || !(matches(repo.Name, keyword) || matches(repo.Description, keyword)) {
+				continue
+			}
+			if language != "" && !strings.EqualFold(repo.Language, language) {
+				continue
+			}
+			results = append(results, gothub.RepositoriesSearchResult{
+				Type:         "repo",
+				Created:      repo.CreatedAt,
+				CreatedAt:    repo.CreatedAt,
+				Watchers:     repo.Watchers,
+				HasDownloads: repo.HasDownloads,
+				Username:     l,
+				Owner:        l,
+				Homepage:     repo.Homepage,
+				Url:          repo.HtmlUrl,
+				Fork:         repo.Fork,
+				HasIssues:    repo.HasIssues,
+				HasWiki:      repo.HasWiki,
+				Forks:        repo.Forks,
+				Size:         repo.Size,
+				Name:         repo.Name,
+				OpenIssues:   repo.OpenIssues,
+				Pushed:       repo.PushedAt,
+				PushedAt:     repo.PushedAt,
+				Description:  repo.Description,
+				Language:     repo.Language,
+				Score:        1,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"repositories": results})
+}
+
+// Finds the users whose login or name contain the keyword; the start page
+// and ordering are ignored.
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
+	keyword := pathKeyword(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := []gothub.UsersSearchResult{}
+	for _, l := range s.logins {
+		a := s.accounts[l]
+		if !matches(a.user.Login, keyword) && !matches(a.user.Name, keyword) {
+			continue
+		}
+		u := s.user(a)
+		results = append(results, gothub.UsersSearchResult{
+			Id:              fmt.Sprintf("user-%d", u.Id),
+			Login:           u.Login,
+			Username:        u.Login,
+			Name:            u.Name,
+			Fullname:        u.Name,
+			Location:        u.Location,
+			Type:            "user",
+			PublicRepoCount: u.PublicRepos,
+			Repos:           u.PublicRepos,
+			Followers:       u.Followers,
+			FollowersCount:  u.Followers,
+			Created:         u.CreatedAt,
+			CreatedAt:       u.CreatedAt,
+			Score:           1,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"users": results})
+}
+
+// Finds the user with the email address, either as their public address or
+// among their emails.
+func (s *Server) searchEmail(w http.ResponseWriter, r *http.Request) {
+	email := pathValue(r, "email")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.logins {
+		a := s.accounts[l]
+		if !strings.EqualFold(a.user.Email, email) && !contains(a.emails, email) {
+			continue
+		}
+		u := s.user(a)
+		writeJSON(w, http.StatusOK, map[string]gothub.EmailSearchResult{"user": {
+			Id:              u.Id,
+			Login:           u.Login,
+			Name:            u.Name,
+			Company:         u.Company,
+			BLog:            u.Blog,
+			Location:        u.Location,
+			Email:           email,
+			Type:            u.Type,
+			PublicRepoCount: u.PublicRepos,
+			PublicGistCount: u.PublicGists,
+			FollowersCount:  u.Followers,
+			FollowingCount:  u.Following,
+			Created:         u.CreatedAt,
+			CreatedAt:       u.CreatedAt,
+		}})
+		return
+	}
+	writeError(w, http.StatusNotFound, "")
+}
+
+// Returns the keyword from the request's path, which gothub query-escapes.
+func pathKeyword(r *http.Request) string {
+	keyword := pathValue(r, "keyword")
+	if unescaped, err := url.QueryUnescape(keyword); err == nil {
+		keyword = unescaped
+	}
+	return keyword
+}
+
+func matches(s, keyword string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(keyword))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes out the page of items selected by the page and per_page query
+// parameters, along with a Link header pointing at the other pages, as the
+// API does for its listings.
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, items interface{}) {
+	list, _ := json.Marshal(items)
+	var all []json.RawMessage
+	json.Unmarshal(list, &all)
+
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	if perPage < 1 {
+		perPage = 30
+	} else if perPage > 100 {
+		perPage = 100
+	}
+	last := (len(all) + perPage - 1) / perPage
+	if last < 1 {
+		last = 1
+	}
+
+	link := func(page int, rel string) string {
+		query.Set("page", strconv.Itoa(page))
+		return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, s.URL, r.URL.Path, query.Encode(), rel)
+	}
+	var links []string
+	if page < last {
+		links = append(links, link(page+1, "next"), link(last, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(all) {
+		start = len(all)
+	}
+	if end > len(all) {
+		end = len(all)
+	}
+	writeJSON(w, http.StatusOK, append([]json.RawMessage{}, all[start:end]...))
+}
+
+// Writes out v as JSON. Like the API, and unlike json.Encoder, it leaves off
+// the trailing newline, which gothub's Emails relies on.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Writes out an error the way the API does. An empty message gets the status
+// code's text.
+func writeError(w http.ResponseWriter, status int, message string, errs ...gothub.Error) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, gothub.ErrorResponse{
+		Message:          message,
+		DocumentationUrl: "https://docs.github.com/rest",
+		Errors:           errs,
+	})
+}
+
+func writeValidation(w http.ResponseWriter, resource, field, code string) {
+	writeError(w, http.StatusUnprocessableEntity, "Validation Failed",
+		gothub.Error{Resource: resource, Field: field, Code: code})
+}
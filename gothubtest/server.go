@@ -0,0 +1,391 @@
+// Package gothubtest provides an in-process fake of the GitHub API, for
+// testing code that uses gothub without talking to GitHub.
+//
+// A Server keeps users, their followers, public keys and emails,
+// organizations and repositories in memory, and serves them from the same
+// endpoints as the API. Seed it with the Add* methods, then get a session
+// pointed at it with Session or Guest:
+//
+//	server := gothubtest.NewServer()
+//	defer server.Close()
+//
+//	server.AddUser(gothub.User{Login: "octocat", Name: "The Octocat"})
+//	server.AddRepository("octocat", gothub.Repository{Name: "hello-world"})
+//
+//	g, err := server.Session("octocat")
+//	...
+//	repos, _, err := g.Repositories()
+//
+// Servers can also run out of calls (see SetRateLimit), and fail requests on
+// demand (see InjectFault).
+package gothubtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nesv/gothub"
+)
+
+// The OAuth scopes granted to the tokens handed out by Session.
+const Scopes = "repo, user, admin:public_key, read:org"
+
+// Makes a Server fail the requests that match it, rather than serve them.
+type Fault struct {
+	// The method and path of the requests to fail. An empty Method matches
+	// any method; Path is a pattern as understood by path.Match, e.g.
+	// "/users/*".
+	Method string
+	Path   string
+
+	// The status code of the response (an HTTP 500 if it is zero), and the
+	// message in its body. An empty Message gets the status code's text.
+	Status  int
+	Message string
+
+	// Extra headers for the response, such as Retry-After.
+	Header http.Header
+
+	// How long to hold the response back for; the request gets no response
+	// at all if its context is done before then.
+	Delay time.Duration
+
+	// How many requests to fail; all of them, when it is zero.
+	Times int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+	ok, _ := path.Match(f.Path, r.URL.Path)
+	return ok
+}
+
+type account struct {
+	user      gothub.User
+	password  string
+	emails    []string
+	following []string
+	keys      []gothub.PublicKey
+	orgs      []string
+	repos     []gothub.Repository
+}
+
+// A fake of the GitHub API, running on a local HTTP server. It is safe for
+// concurrent use.
+type Server struct {
+	// The URL of the server, which is also its base URL for sessions.
+	URL string
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	nextId   int
+	accounts map[string]*account
+	logins   []string
+	orgs     map[string]*gothub.Organization
+	orgNames []string
+	tokens   map[string]string
+	faults   []*Fault
+
+	rateLimit     int
+	rateRemaining int
+	rateReset     time.Time
+	requests      int
+}
+
+// Starts a new, empty Server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		nextId:    1,
+		accounts:  make(map[string]*account),
+		orgs:      make(map[string]*gothub.Organization),
+		tokens:    make(map[string]string),
+		rateLimit: 5000,
+	}
+	s.rateRemaining = s.rateLimit
+	s.server = httptest.NewServer(s.routes())
+	s.URL = s.server.URL
+	return s
+}
+
+// Shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Returns the number of requests the server has been sent.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Creates a session that is authenticated as the given user, with a token
+// granted the OAuth Scopes. The options are the same as for gothub.New, and
+// apply on top of the server's base URL and token.
+func (s *Server) Session(login string, opts ...gothub.Option) (*gothub.GitHub, error) {
+	token, err := s.Token(login)
+	if err != nil {
+		return nil, err
+	}
+	base := []gothub.Option{gothub.WithBaseUrl(s.URL, ""), gothub.WithToken(token)}
+	return gothub.New(append(base, opts...)...)
+}
+
+// Creates a session without any credentials.
+func (s *Server) Guest(opts ...gothub.Option) (*gothub.GitHub, error) {
+	return gothub.New(append([]gothub.Option{gothub.WithBaseUrl(s.URL, "")}, opts...)...)
+}
+
+// Hands out a new token that authenticates as the given user.
+func (s *Server) Token(login string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[login]; !ok {
+		return "", errors.New(fmt.Sprintf("No user %q", login))
+	}
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := "ghp_" + hex.EncodeToString(b)
+	s.tokens[token] = login
+	return token, nil
+}
+
+// Adds a user, filling in its ID, URLs and creation time if they are not set,
+// and returns it as the API will. An existing user with the same login is
+// replaced, along with everything that belonged to it.
+func (s *Server) AddUser(user gothub.User) gothub.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.Id == 0 {
+		user.Id = s.id()
+	}
+	if user.Type == "" {
+		user.Type = "User"
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	user.Url = fmt.Sprintf("%s/users/%s", s.URL, user.Login)
+	user.HtmlUrl = fmt.Sprintf("https://github.com/%s", user.Login)
+	user.AvatarUrl = fmt.Sprintf("https://avatars.githubusercontent.com/u/%d?v=4", user.Id)
+
+	if _, ok := s.accounts[user.Login]; !ok {
+		s.logins = append(s.logins, user.Login)
+	}
+	s.accounts[user.Login] = &account{user: user}
+	return user
+}
+
+// Sets the password the user can log in with, using basic authentication.
+func (s *Server) SetPassword(login, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.accounts[login]; ok {
+		a.password = password
+	}
+}
+
+// Makes one user follow another; both have to have been added.
+func (s *Server) AddFollower(login, follower string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.follow(follower, login)
+}
+
+// Adds email addresses to the user's account.
+func (s *Server) AddEmails(login string, emails ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.accounts[login]; ok {
+		a.emails = append(a.emails, emails...)
+	}
+}
+
+// Adds a public key to the user's account, and returns its ID.
+func (s *Server) AddPublicKey(login string, key gothub.PublicKey) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.accounts[login]
+	if !ok {
+		return 0
+	}
+	return s.addKey(a, key).Id
+}
+
+// Adds an organization, with the given users as its members, and returns it
+// as the API will.
+func (s *Server) AddOrganization(org gothub.Organization, members ...string) gothub.Organization {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if org.Id == 0 {
+		org.Id = s.id()
+	}
+	org.Type = "Organization"
+	if org.CreatedAt.IsZero() {
+		org.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	org.Url = fmt.Sprintf("%s/orgs/%s", s.URL, org.Login)
+	org.HtmlUrl = fmt.Sprintf("https://github.com/%s", org.Login)
+	org.ReposUrl = org.Url + "/repos"
+	org.EventsUrl = org.Url + "/events"
+	org.MembersUrl = org.Url + "/members{/member}"
+	org.PublicMembersUrl = org.Url + "/public_members{/member}"
+
+	if _, ok := s.orgs[org.Login]; !ok {
+		s.orgNames = append(s.orgNames, org.Login)
+	}
+	s.orgs[org.Login] = &org
+	for _, member := range members {
+		if a, ok := s.accounts[member]; ok {
+			a.orgs = append(a.orgs, org.Login)
+		}
+	}
+	return org
+}
+
+// Adds a repository to the user's account, filling in its ID, owner and URLs,
+// and returns it as the API will.
+func (s *Server) AddRepository(owner string, repo gothub.Repository) gothub.Repository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.accounts[owner]
+	if !ok {
+		return repo
+	}
+	if repo.Id == 0 {
+		repo.Id = s.id()
+	}
+	if repo.DefaultBranch == "" {
+		repo.DefaultBranch = "main"
+	}
+	if repo.CreatedAt.IsZero() {
+		repo.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	repo.Owner = s.owner(a.user)
+	repo.FullName = owner + "/" + repo.Name
+	repo.Url = fmt.Sprintf("%s/repos/%s", s.URL, repo.FullName)
+	repo.HtmlUrl = "https://github.com/" + repo.FullName
+	repo.CloneUrl = repo.HtmlUrl + ".git"
+	repo.SshUrl = fmt.Sprintf("git@github.com:%s.git", repo.FullName)
+	repo.Permissions = gothub.RepositoryPermissions{Admin: true, Pull: true, Push: true}
+
+	a.repos = append(a.repos, repo)
+	return repo
+}
+
+// Sets the rate limit the server enforces, how many calls are left of it, and
+// when the current window ends (after which the calls are topped up again).
+// Once a window runs out of calls, requests are turned away with an HTTP 403,
+// as the API does. A zero reset time ends the window an hour from now.
+func (s *Server) SetRateLimit(limit, remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reset.IsZero() {
+		reset = time.Now().Add(time.Hour)
+	}
+	s.rateLimit, s.rateRemaining, s.rateReset = limit, remaining, reset.Truncate(time.Second)
+}
+
+// Makes the server fail the requests that match the fault. Faults are checked
+// in the order they were injected, and apply until they have failed as many
+// requests as they were set up to, or until ClearFaults is called.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Removes all of the injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// The methods below expect s.mu to be held.
+
+func (s *Server) id() int {
+	id := s.nextId
+	s.nextId++
+	return id
+}
+
+func (s *Server) follow(login, target string) bool {
+	a, ok := s.accounts[login]
+	if _, exists := s.accounts[target]; !ok || !exists {
+		return false
+	}
+	for _, f := range a.following {
+		if f == target {
+			return true
+		}
+	}
+	a.following = append(a.following, target)
+	return true
+}
+
+func (s *Server) addKey(a *account, key gothub.PublicKey) gothub.PublicKey {
+	if key.Id == 0 {
+		key.Id = s.id()
+	}
+	key.Url = fmt.Sprintf("%s/user/keys/%d", s.URL, key.Id)
+	a.keys = append(a.keys, key)
+	return key
+}
+
+func (s *Server) owner(u gothub.User) gothub.RepositoryOwner {
+	return gothub.RepositoryOwner{
+		AvatarUrl:        u.AvatarUrl,
+		EventsUrl:        u.Url + "/events{/privacy}",
+		FollowersUrl:     u.Url + "/followers",
+		FollowingUrl:     u.Url + "/following{/other_user}",
+		GistsUrl:         u.Url + "/gists{/gist_id}",
+		HtmlUrl:          u.HtmlUrl,
+		Id:               u.Id,
+		Login:            u.Login,
+		OrganizationsUrl: u.Url + "/orgs",
+		ReposUrl:         u.Url + "/repos",
+		StarredUrl:       u.Url + "/starred{/owner}{/repo}",
+		SubscriptionsUrl: u.Url + "/subscriptions",
+		Type:             u.Type,
+		Url:              u.Url,
+	}
+}
+
+func (s *Server) follower(u gothub.User) gothub.Follower {
+	o := s.owner(u)
+	return gothub.Follower{
+		Login:             o.Login,
+		Id:                o.Id,
+		AvatarUrl:         o.AvatarUrl,
+		Url:               o.Url,
+		HtmlUrl:           o.HtmlUrl,
+		FollowersUrl:      o.FollowersUrl,
+		FollowingUrl:      o.FollowingUrl,
+		GistsUrl:          o.GistsUrl,
+		StarredUrl:        o.StarredUrl,
+		SubscriptionsUrl:  o.SubscriptionsUrl,
+		OrganizationsUrl:  o.OrganizationsUrl,
+		ReposUrl:          o.ReposUrl,
+		EventsUrl:         o.EventsUrl,
+		ReceivedEventsUrl: u.Url + "/received_events",
+		Type:              o.Type,
+	}
+}
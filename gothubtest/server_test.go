@@ -0,0 +1,377 @@
+package gothubtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nesv/gothub"
+)
+
+// Starts a server with a couple of users, and returns it along with a
+// session for octocat.
+func newTestServer(t *testing.T) (*Server, *gothub.GitHub) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddUser(gothub.User{Login: "octocat", Name: "The Octocat", Email: "octocat@github.com"})
+	server.AddUser(gothub.User{Login: "hubot", Name: "Hubot"})
+	server.AddFollower("octocat", "hubot")
+
+	g, err := server.Session("octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, g
+}
+
+func TestServerUsers(t *testing.T) {
+	_, g := newTestServer(t)
+
+	user, _, err := g.GetCurrentUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Login != "octocat" || user.Name != "The Octocat" || user.Id == 0 || user.Followers != 1 {
+		t.Errorf("Unexpected current user %+v", user)
+	}
+	if scopes, _ := g.OAuthScopes(); len(scopes) == 0 {
+		t.Error("Expected the token's scopes to be reported")
+	}
+
+	followers, _, err := user.GetFollowers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(followers) != 1 || followers[0].Login != "hubot" {
+		t.Errorf("Unexpected followers %+v", followers)
+	}
+
+	if following, _, err := g.IsFollowing("hubot"); err != nil || following {
+		t.Errorf("Expected octocat not to follow hubot, got %t, %v", following, err)
+	}
+	if _, err := g.Follow("hubot"); err != nil {
+		t.Fatal(err)
+	}
+	if following, _, err := g.IsFollowing("hubot"); err != nil || !following {
+		t.Errorf("Expected octocat to follow hubot, got %t, %v", following, err)
+	}
+	hubot, _, err := g.GetUser("hubot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hubot.Followers != 1 || hubot.Following != 1 {
+		t.Errorf("Unexpected counts for %+v", hubot)
+	}
+	if _, err := g.Unfollow("hubot"); err != nil {
+		t.Fatal(err)
+	}
+	if following, _, _ := g.IsFollowing("hubot"); following {
+		t.Error("Expected octocat to have unfollowed hubot")
+	}
+
+	if _, err := g.Follow("nobody"); !gothub.IsNotFound(err) {
+		t.Errorf("Expected following a missing user to fail, got %v", err)
+	}
+	if _, _, err := g.GetUser("nobody"); !gothub.IsNotFound(err) {
+		t.Errorf("Expected a missing user to be not found, got %v", err)
+	}
+}
+
+func TestServerEmails(t *testing.T) {
+	server, g := newTestServer(t)
+
+	// An account without emails has none, rather than a single empty one.
+	emails, _, err := g.Emails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 0 {
+		t.Errorf("Expected no emails, got %q", emails)
+	}
+
+	server.AddEmails("octocat", "octocat@github.com")
+	if _, err := g.AddEmails([]string{"octocat@example.com", "cat@example.com", `"cat, the"@example.com`}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.DeleteEmails([]string{"cat@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	emails, _, err = g.Emails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 3 || emails[0] != "octocat@github.com" || emails[1] != "octocat@example.com" || emails[2] != `"cat, the"@example.com` {
+		t.Errorf("Unexpected emails %q", emails)
+	}
+
+	// Like the API, the server only takes a JSON array of addresses.
+	token, err := server.Token("octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("POST", server.URL+"/user/emails", strings.NewReader("[cat@example.com]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "token "+token)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a body that is not JSON to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerPublicKeys(t *testing.T) {
+	server, g := newTestServer(t)
+	seeded := server.AddPublicKey("octocat", gothub.PublicKey{Title: "laptop", Key: "ssh-ed25519 AAAA1"})
+
+	id, _, err := g.AddPublicKey("desktop", "ssh-ed25519 AAAA2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := g.GetPublicKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Title != "desktop" || key.Key != "ssh-ed25519 AAAA2" {
+		t.Errorf("Unexpected key %+v", key)
+	}
+	if _, _, err := g.AddPublicKey("again", "ssh-ed25519 AAAA1"); !gothub.IsValidation(err) {
+		t.Errorf("Expected a key in use to be rejected, got %v", err)
+	}
+
+	if _, err := g.RemovePublicKey(seeded); err != nil {
+		t.Fatal(err)
+	}
+	keys, _, err := g.PublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Id != id {
+		t.Errorf("Unexpected keys %+v", keys)
+	}
+	if _, _, err := g.GetPublicKey(seeded); !gothub.IsNotFound(err) {
+		t.Errorf("Expected the removed key to be not found, got %v", err)
+	}
+}
+
+func TestServerOrganizationsAndRepositories(t *testing.T) {
+	server, g := newTestServer(t)
+	server.AddOrganization(gothub.Organization{Login: "github", Name: "GitHub"}, "octocat")
+	for _, name := range []string{"one", "two", "three", "four", "five"} {
+		server.AddRepository("octocat", gothub.Repository{Name: name})
+	}
+	server.AddRepository("octocat", gothub.Repository{Name: "secret", Private: true})
+
+	org, _, err := g.GetOrganization("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.Name != "GitHub" || org.Type != "Organization" {
+		t.Errorf("Unexpected organization %+v", org)
+	}
+	orgs, _, err := g.Organizations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgs) != 1 || orgs[0].Login != "github" {
+		t.Errorf("Unexpected organizations %+v", orgs)
+	}
+
+	ctx := context.Background()
+	it := g.ListRepositories(&gothub.ListOptions{PerPage: 2})
+	var names []string
+	for it.Next(ctx) {
+		names = append(names, it.Value().Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 6 || names[0] != "one" || names[5] != "secret" {
+		t.Errorf("Unexpected repositories %q", names)
+	}
+	if resp := it.Response(); resp.LastPage != 0 || resp.PrevPage != 2 {
+		t.Errorf("Unexpected last page %+v", resp)
+	}
+
+	hubot, err := server.Session("hubot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	octocat, _, err := hubot.GetUser("octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, _, err := octocat.Repositories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 5 || repos[0].FullName != "octocat/one" || repos[0].Owner.Login != "octocat" {
+		t.Errorf("Expected octocat's public repositories, got %+v", repos)
+	}
+}
+
+func TestServerSearch(t *testing.T) {
+	server, g := newTestServer(t)
+	server.AddEmails("hubot", "hubot@example.com")
+	server.AddRepository("octocat", gothub.Repository{Name: "hello-world", Language: "Go"})
+	server.AddRepository("hubot", gothub.Repository{Name: "scripts", Description: "Hello, world"})
+
+	repos, _, err := g.SearchRepositories(&gothub.RepositoriesSearchParam{Keyword: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 || repos[0].Owner != "octocat" || repos[1].Name != "scripts" {
+		t.Errorf("Unexpected repositories %+v", repos)
+	}
+	repos, _, err = g.SearchRepositories(&gothub.RepositoriesSearchParam{Keyword: "hello", Language: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].Name != "hello-world" {
+		t.Errorf("Unexpected Go repositories %+v", repos)
+	}
+
+	users, _, err := g.SearchUsers(&gothub.UsersSearchParam{Keyword: "the octo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Login != "octocat" {
+		t.Errorf("Unexpected users %+v", users)
+	}
+
+	user, _, err := g.SearchEmail(&gothub.EmailSearchParam{Email: "hubot@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Login != "hubot" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+	if _, _, err := g.SearchEmail(&gothub.EmailSearchParam{Email: "nobody@example.com"}); !gothub.IsNotFound(err) {
+		t.Errorf("Expected an unknown email to be not found, got %v", err)
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.SetPassword("hubot", "s3cret")
+
+	guest, err := server.Guest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := guest.GetUser("octocat"); err != nil {
+		t.Errorf("Expected guests to be able to get users, got %v", err)
+	}
+	if _, _, err := guest.GetCurrentUser(); !hasStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Expected an HTTP 401 for a guest, got %v", err)
+	}
+
+	g, err := server.Guest(gothub.WithBasicAuth("hubot", "s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, _, err := g.GetCurrentUser(); err != nil || user.Login != "hubot" {
+		t.Errorf("Expected to be logged in as hubot, got %+v, %v", user, err)
+	}
+
+	g, err = server.Guest(gothub.WithToken("ghp_bogus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.GetUser("octocat"); !hasStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Expected a bad token to be turned away, got %v", err)
+	}
+
+	if _, err := server.Session("nobody"); err == nil {
+		t.Error("Expected no session for a missing user")
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	server, g := newTestServer(t)
+	reset := time.Now().Add(time.Hour)
+	server.SetRateLimit(60, 2, reset)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := g.GetUser("hubot"); err != nil {
+			t.Fatalf("Call #%d: %s", i+1, err)
+		}
+	}
+	rate := g.Rate()
+	if rate.Limit != 60 || rate.Remaining != 0 || rate.Reset.Unix() != reset.Unix() {
+		t.Errorf("Unexpected rate %+v", rate)
+	}
+
+	// The session knows it is out of calls, so ask with a fresh one.
+	other, err := server.Session("hubot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := other.GetUser("octocat"); !errors.Is(err, gothub.ErrRateLimitReached) {
+		t.Errorf("Expected the rate limit to be reached, got %v", err)
+	}
+
+	// Once the window is over, the calls are topped up.
+	server.SetRateLimit(60, 0, time.Now().Add(-time.Second))
+	if other, err = server.Session("hubot"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := other.GetUser("octocat"); err != nil {
+		t.Errorf("Expected a new window, got %v", err)
+	}
+	if requests := server.Requests(); requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	server, g := newTestServer(t)
+	server.InjectFault(Fault{Method: "GET", Path: "/users/*", Status: http.StatusBadGateway, Times: 1})
+
+	if _, _, err := g.GetUser("hubot"); !hasStatus(err, http.StatusBadGateway) {
+		t.Errorf("Expected an HTTP 502, got %v", err)
+	}
+	if _, _, err := g.GetUser("hubot"); err != nil {
+		t.Errorf("Expected the fault to apply once, got %v", err)
+	}
+
+	retried := 0
+	g, err := server.Session("octocat", gothub.WithRetryPolicy(gothub.RetryPolicy{
+		MaxRetries: 2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+		OnRetry:    func(gothub.RetryEvent) { retried++ },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.InjectFault(Fault{Path: "/user", Status: http.StatusServiceUnavailable, Times: 2})
+	if _, _, err := g.GetCurrentUser(); err != nil || retried != 2 {
+		t.Errorf("Expected to get through after 2 retries, got %d, %v", retried, err)
+	}
+
+	server.InjectFault(Fault{Path: "/orgs/*", Delay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.GetOrganizationContext(ctx, "github"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to time out, got %v", err)
+	}
+
+	server.ClearFaults()
+	if _, _, err := g.GetOrganization("github"); !gothub.IsNotFound(err) {
+		t.Errorf("Expected the faults to be cleared, got %v", err)
+	}
+}
+
+func hasStatus(err error, status int) bool {
+	var e *gothub.ErrorResponse
+	return errors.As(err, &e) && e.Response.StatusCode == status
+}
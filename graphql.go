@@ -0,0 +1,444 @@
+package gothub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A GraphQL ID, for variables of the ID type.
+type GraphQLId string
+
+// The page info of a GraphQL connection, for paginating through it with
+// QueryPages.
+type PageInfo struct {
+	HasNextPage     bool
+	EndCursor       string
+	HasPreviousPage bool
+	StartCursor     string
+}
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphqlError  `json:"errors"`
+}
+
+type graphqlError struct {
+	Message string        `json:"message"`
+	Type    string        `json:"type"`
+	Path    []interface{} `json:"path"`
+}
+
+/*
+Runs a GraphQL query built from the shape of q, which has to be a pointer to a
+struct, and unmarshals the data that comes back into it.
+
+Each field of the struct asks for the field of the same name, with its first
+letter in lower case, or for what its graphql tag says, which is where
+arguments and variables go. Struct fields ask for their own fields in turn,
+and embedded structs tagged with an inline fragment ("... on User") ask for
+theirs when the object is of that type:
+
+	var q struct {
+	    Repository struct {
+	        Description string
+	        Stargazers  struct {
+	            TotalCount int
+	        } `graphql:"stargazers"`
+	    } `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	_, err := g.Query(ctx, &q, map[string]interface{}{
+	    "owner": "octocat",
+	    "name":  "hello-world",
+	})
+
+Since the data is unmarshalled with encoding/json, fields that are given an
+alias have to be named after it.
+
+The types of the variables follow from their Go types: strings are String!,
+ints are Int!, bools are Boolean!, GraphQLIds are ID!, pointers are nullable
+and slices are lists. Named types stand for the GraphQL type of the same
+name, so an enum or custom scalar is a named string type, and an input object
+a named struct; other named types, such as time.Duration, are rejected.
+
+Selections cannot be recursive, so a struct that contains itself, even through
+a pointer or a slice, is rejected too.
+
+The query is sent to the session's GraphQL endpoint, with its credentials.
+Any errors it comes back with are turned into an *ErrorResponse, along with
+whatever data did come back.
+*/
+func (g *GitHub) Query(ctx context.Context, q interface{}, variables map[string]interface{}) (*Response, error) {
+	return g.graphqlOperation(ctx, "query", q, variables)
+}
+
+// Like Query, but runs a mutation built from the shape of m. Its input usually
+// goes in an "input" variable, of a named struct type:
+//
+//	type AddStarInput struct {
+//	    StarrableId gothub.GraphQLId `json:"starrableId"`
+//	}
+//
+//	var m struct {
+//	    AddStar struct {
+//	        Starrable struct {
+//	            StargazerCount int
+//	        }
+//	    } `graphql:"addStar(input: $input)"`
+//	}
+//	_, err := g.Mutate(ctx, &m, map[string]interface{}{
+//	    "input": AddStarInput{StarrableId: repoId},
+//	})
+func (g *GitHub) Mutate(ctx context.Context, m interface{}, variables map[string]interface{}) (*Response, error) {
+	return g.graphqlOperation(ctx, "mutation", m, variables)
+}
+
+func (g *GitHub) graphqlOperation(ctx context.Context, operation string, v interface{}, variables map[string]interface{}) (*Response, error) {
+	query, err := graphqlQuery(operation, v, variables)
+	if err != nil {
+		return nil, err
+	}
+	return g.GraphQL(ctx, v, query, variables)
+}
+
+/*
+Runs a paginated query for every page of a connection, like Query. The end
+cursor of each page is passed to the next in the variable named by
+cursorVar, which should be declared by the query as a nullable String, and
+is null for the first page:
+
+	var q struct {
+	    Viewer struct {
+	        Repositories struct {
+	            Nodes    []struct{ Name string }
+	            PageInfo gothub.PageInfo
+	        } `graphql:"repositories(first: 100, after: $cursor)"`
+	    }
+	}
+	var names []string
+	_, err := g.QueryPages(ctx, &q, nil, "cursor", func() (gothub.PageInfo, error) {
+	    for _, repo := range q.Viewer.Repositories.Nodes {
+	        names = append(names, repo.Name)
+	    }
+	    return q.Viewer.Repositories.PageInfo, nil
+	})
+
+After each page, page is called to collect the page's nodes, and return the
+connection's page info. An error from it stops the pagination, and is
+returned. The response the last page came in is returned too.
+*/
+func (g *GitHub) QueryPages(ctx context.Context, q interface{}, variables map[string]interface{}, cursorVar string, page func() (PageInfo, error)) (*Response, error) {
+	vars := make(map[string]interface{}, len(variables)+1)
+	for name, value := range variables {
+		vars[name] = value
+	}
+	vars[cursorVar] = (*string)(nil)
+
+	for {
+		resp, err := g.Query(ctx, q, vars)
+		if err != nil {
+			return resp, err
+		}
+		info, err := page()
+		if err != nil || !info.HasNextPage {
+			return resp, err
+		}
+		cursor := info.EndCursor
+		vars[cursorVar] = &cursor
+	}
+}
+
+/*
+Sends a hand-written GraphQL query (or mutation) with the given variables,
+and unmarshals the data that comes back into v, unless it is nil:
+
+	var data struct {
+	    Viewer struct{ Login string }
+	}
+	_, err := g.GraphQL(ctx, &data, "query { viewer { login } }", nil)
+
+Like Query, any errors the query comes back with are turned into an
+*ErrorResponse.
+*/
+func (g *GitHub) GraphQL(ctx context.Context, v interface{}, query string, variables map[string]interface{}) (*Response, error) {
+	if g.graphqlRateLimited() {
+		if !g.waitForRateLimit {
+			return nil, ErrRateLimitReached
+		}
+		if err := sleep(ctx, time.Until(g.GraphQLRate().Reset)+time.Second); err != nil {
+			return nil, err
+		}
+	}
+
+	if !isGraphQLMutation(query) {
+		ctx = context.WithValue(ctx, graphqlQueryKey{}, true)
+	}
+	response, err := g.do(ctx, "POST", g.graphqlUrl(), nil, graphqlRequest{Query: query, Variables: variables})
+	if response != nil {
+		g.updateGraphQLRate(response)
+	}
+	resp := newResponse(response)
+	if err != nil {
+		return resp, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return resp, err
+	}
+	var result graphqlResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return resp, err
+	}
+
+	if v != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, v); err != nil {
+			return resp, err
+		}
+	}
+	if len(result.Errors) > 0 {
+		response.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return resp, newGraphQLError(response, result.Errors)
+	}
+	return resp, nil
+}
+
+// Marks the requests of GraphQL queries, which only read, unlike the POST
+// requests they are sent as; see isMutation.
+type graphqlQueryKey struct{}
+
+// Reports whether the GraphQL document is a mutation, rather than a query
+// (or subscription), going by its first keyword.
+func isGraphQLMutation(query string) bool {
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if !strings.HasPrefix(query, "#") {
+			break
+		}
+		// Skip the comment.
+		if i := strings.IndexByte(query, '\n'); i >= 0 {
+			query = query[i:]
+		} else {
+			return false
+		}
+	}
+	if !strings.HasPrefix(query, "mutation") {
+		return false
+	}
+	rest := strings.TrimPrefix(query, "mutation")
+	r, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Turns the errors of a GraphQL response into an *ErrorResponse. Each error
+// keeps its message, and has its type (such as "NOT_FOUND") as its code, and
+// the path of the field it is about as its field.
+func newGraphQLError(r *http.Response, errs []graphqlError) *ErrorResponse {
+	e := &ErrorResponse{Response: r, Message: errs[0].Message}
+	if len(errs) > 1 {
+		e.Message = fmt.Sprintf("%s (and %d more GraphQL errors)", e.Message, len(errs)-1)
+	}
+	for _, ge := range errs {
+		path := make([]string, len(ge.Path))
+		for i, p := range ge.Path {
+			path[i] = fmt.Sprint(p)
+		}
+		e.Errors = append(e.Errors, Error{
+			Resource: "GraphQL",
+			Field:    strings.Join(path, "."),
+			Code:     ge.Type,
+			Message:  ge.Message,
+		})
+	}
+	return e
+}
+
+// Returns the URL of the session's GraphQL endpoint: "/graphql" on
+// api.github.com, and "/api/graphql" on a GitHub Enterprise Server install.
+func (g *GitHub) graphqlUrl() string {
+	base := strings.TrimRight(g.apiUrl(""), "/")
+	if strings.HasSuffix(base, "/api/v3") {
+		base = strings.TrimSuffix(base, "/v3")
+	}
+	return base + "/graphql"
+}
+
+// Reports whether the request is for the session's GraphQL endpoint, which
+// has a rate limit of its own.
+func (g *GitHub) isGraphQL(req *http.Request) bool {
+	u, err := url.Parse(g.graphqlUrl())
+	return err == nil && req.URL.Host == u.Host && req.URL.Path == u.Path
+}
+
+// Returns the session's GraphQL rate limit, which is counted in points rather
+// than requests, as of the last GraphQL response. See
+// https://docs.github.com/en/graphql/overview/rate-limits-and-node-limits-for-the-graphql-api
+func (g *GitHub) GraphQLRate() Rate {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.graphqlRate
+}
+
+func (g *GitHub) graphqlRateLimited() bool {
+	rate := g.GraphQLRate()
+	return rate.Limit > 0 && rate.Remaining == 0 && time.Now().Before(rate.Reset)
+}
+
+func (g *GitHub) updateGraphQLRate(r *http.Response) {
+	rate, ok := parseRate(r.Header)
+	if !ok {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.graphqlRate = rate
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	graphqlIdType   = reflect.TypeOf(GraphQLId(""))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Builds a GraphQL operation out of the shape of v, declaring the variables.
+func graphqlQuery(operation string, v interface{}, variables map[string]interface{}) (string, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return "", errors.New(fmt.Sprintf("Cannot build a GraphQL %s out of %T", operation, v))
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var declarations []string
+	for _, name := range names {
+		typ, err := graphqlType(reflect.TypeOf(variables[name]))
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("GraphQL variable $%s: %s", name, err))
+		}
+		declarations = append(declarations, fmt.Sprintf("$%s:%s", name, typ))
+	}
+
+	query := operation
+	if len(declarations) > 0 {
+		query += "(" + strings.Join(declarations, ",") + ")"
+	}
+	selection, err := graphqlSelection(t, make(map[reflect.Type]bool))
+	if err != nil {
+		return "", err
+	}
+	return query + selection, nil
+}
+
+// Returns the GraphQL type of variables of the Go type.
+func graphqlType(t reflect.Type) (string, error) {
+	if t == nil {
+		return "", errors.New("untyped nil")
+	}
+
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		nullable, t = true, t.Elem()
+	}
+
+	var name string
+	switch {
+	case t == graphqlIdType:
+		name = "ID"
+	case t == timeType:
+		name = "DateTime"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		elem, err := graphqlType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		name = "[" + elem + "]"
+	case t.PkgPath() != "" && t.Name() != "":
+		// Only enums, custom scalars and input objects have names of their
+		// own; a time.Duration, say, is not a GraphQL type.
+		if t.Kind() != reflect.String && t.Kind() != reflect.Struct {
+			return "", errors.New(fmt.Sprintf("no GraphQL type for %s; use a named string or struct type, or a built-in one", t))
+		}
+		name = t.Name()
+	case t.Kind() == reflect.String:
+		name = "String"
+	case t.Kind() == reflect.Bool:
+		name = "Boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		name = "Int"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		name = "Float"
+	default:
+		return "", errors.New(fmt.Sprintf("no GraphQL type for %s; use a named type", t))
+	}
+
+	if !nullable {
+		name += "!"
+	}
+	return name, nil
+}
+
+// Returns the selection set for the Go type: the fields of a struct, and the
+// selection sets of those that are objects themselves. Scalars have none.
+// The structs whose fields are being selected are kept in path, since a
+// struct that contains itself would make for an endless selection.
+func graphqlSelection(t reflect.Type, path map[reflect.Type]bool) (string, error) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return "", nil
+	}
+	if path[t] {
+		return "", errors.New(fmt.Sprintf("Cannot build a GraphQL selection out of %s, which contains itself", t))
+	}
+	path[t] = true
+	defer delete(path, t)
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("graphql")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		selection, err := graphqlSelection(f.Type, path)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case f.Anonymous && tag == "":
+			// An embedded struct without a fragment shares its fields.
+			if selection != "" {
+				fields = append(fields, selection[1:len(selection)-1])
+			}
+		case tag != "":
+			fields = append(fields, tag+selection)
+		default:
+			r, size := utf8.DecodeRuneInString(f.Name)
+			fields = append(fields, string(unicode.ToLower(r))+f.Name[size:]+selection)
+		}
+	}
+	return "{" + strings.Join(fields, ",") + "}", nil
+}
@@ -0,0 +1,321 @@
+package gothub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type graphqlRepositoryOrder string
+
+type graphqlStarInput struct {
+	StarrableId GraphQLId `json:"starrableId"`
+}
+
+type graphqlComment struct {
+	Body    string
+	Replies []graphqlComment
+}
+
+type graphqlThread struct {
+	Title  string
+	Parent *struct {
+		Thread *graphqlThread
+	}
+}
+
+func TestGraphQLQueryBuilding(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login     string
+			CreatedAt time.Time
+			Ignored   string `graphql:"-"`
+		}
+		Repository struct {
+			Name     string
+			Issues   []struct{ Title string } `graphql:"issues(first: $first)"`
+			Ordering string                   `graphql:"ordering: description"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+		Node struct {
+			Id   GraphQLId
+			User struct {
+				Bio string
+			} `graphql:"... on User"`
+		} `graphql:"node(id: $id)"`
+	}
+
+	query, err := graphqlQuery("query", &q, map[string]interface{}{
+		"owner": "octocat",
+		"name":  "hello-world",
+		"first": 10,
+		"id":    GraphQLId("MDQ6VXNlcjE="),
+		"after": (*string)(nil),
+		"order": []graphqlRepositoryOrder{"STARS"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "query($after:String,$first:Int!,$id:ID!,$name:String!,$order:[graphqlRepositoryOrder!]!,$owner:String!)" +
+		"{viewer{login,createdAt},repository(owner: $owner, name: $name){name,issues(first: $first){title},ordering: description},node(id: $id){id,... on User{bio}}}"
+	if query != expected {
+		t.Errorf("Unexpected query\n%s\nexpected\n%s", query, expected)
+	}
+
+	var m struct {
+		AddStar struct {
+			ClientMutationId string
+		} `graphql:"addStar(input: $input)"`
+	}
+	query, err = graphqlQuery("mutation", &m, map[string]interface{}{"input": graphqlStarInput{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "mutation($input:graphqlStarInput!){addStar(input: $input){clientMutationId}}"; query != expected {
+		t.Errorf("Expected %q, got %q", expected, query)
+	}
+
+	if _, err := graphqlQuery("query", q, nil); err == nil {
+		t.Error("Expected a struct that is not a pointer to be rejected")
+	}
+	if _, err := graphqlQuery("query", &q, map[string]interface{}{"x": nil}); err == nil {
+		t.Error("Expected an untyped nil variable to be rejected")
+	}
+	if _, err := graphqlQuery("query", &q, map[string]interface{}{"timeout": time.Second}); err == nil {
+		t.Error("Expected a time.Duration variable to be rejected")
+	}
+}
+
+func TestGraphQLRecursiveSelection(t *testing.T) {
+	var direct struct {
+		Comment graphqlComment `graphql:"comment(id: $id)"`
+	}
+	if _, err := graphqlQuery("query", &direct, nil); err == nil {
+		t.Error("Expected a struct that contains itself to be rejected")
+	}
+
+	var indirect struct {
+		Thread graphqlThread
+	}
+	if _, err := graphqlQuery("query", &indirect, nil); err == nil {
+		t.Error("Expected a struct that contains itself through another to be rejected")
+	}
+
+	// The same struct may appear more than once, as long as it is not
+	// within itself.
+	var siblings struct {
+		First, Last struct{ Body string }
+		Pinned      *graphqlStarInput
+		Starred     []graphqlStarInput
+	}
+	query, err := graphqlQuery("query", &siblings, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "query{first{body},last{body},pinned{starrableId},starred{starrableId}}"; query != expected {
+		t.Errorf("Expected %q, got %q", expected, query)
+	}
+}
+
+// Starts a fake GraphQL API, which hands each request it gets to answer and
+// counts GraphQL points down from 5000. Returns a session pointed at it, made
+// with the given options as well.
+func newGraphQLServer(t *testing.T, answer func(query string, variables map[string]interface{}) string, opts ...Option) (*GitHub, *[]string) {
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+	remaining := 5000
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/graphql" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var request struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		queries = append(queries, request.Query)
+		remaining--
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(remaining))
+		w.Header().Set("X-RateLimit-Used", fmt.Sprint(5000-remaining))
+		w.Header().Set("X-RateLimit-Resource", "graphql")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, answer(request.Query, request.Variables))
+	}, opts...)
+	return g, &queries
+}
+
+func TestGraphQLQuery(t *testing.T) {
+	g, queries := newGraphQLServer(t, func(query string, variables map[string]interface{}) string {
+		return fmt.Sprintf(`{"data": {"repository": {"name": %q, "stargazers": {"totalCount": 42}}}}`, variables["name"])
+	}, WithToken("secret"))
+	g.RateLimit, g.RateLimitRemaining = 60, 60
+
+	var q struct {
+		Repository struct {
+			Name       string
+			Stargazers struct {
+				TotalCount int
+			}
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	resp, err := g.Query(context.Background(), &q, map[string]interface{}{"owner": "octocat", "name": "hello-world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Repository.Name != "hello-world" || q.Repository.Stargazers.TotalCount != 42 {
+		t.Errorf("Unexpected data %+v", q)
+	}
+	if len(*queries) != 1 {
+		t.Fatalf("Expected a single query, got %d", len(*queries))
+	}
+
+	if resp.Rate.Remaining != 4999 {
+		t.Errorf("Expected the response to have the GraphQL rate, got %+v", resp.Rate)
+	}
+	if rate := g.GraphQLRate(); rate.Limit != 5000 || rate.Remaining != 4999 || rate.Used != 1 {
+		t.Errorf("Unexpected GraphQL rate %+v", rate)
+	}
+	if g.RateLimit != 60 || g.RateLimitRemaining != 60 {
+		t.Errorf("Expected the REST rate limit to be left alone, got %d/%d", g.RateLimitRemaining, g.RateLimit)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	g, _ := newGraphQLServer(t, func(query string, variables map[string]interface{}) string {
+		return `{
+			"data": {"viewer": {"login": "octocat"}, "repository": null},
+			"errors": [
+				{"type": "NOT_FOUND", "path": ["repository"], "message": "Could not resolve to a Repository with the name 'octocat/nope'."},
+				{"type": "FORBIDDEN", "path": ["repository", "issues", 0], "message": "Resource not accessible by integration"}
+			]
+		}`
+	})
+
+	var data struct {
+		Viewer     struct{ Login string }
+		Repository *struct{ Name string }
+	}
+	_, err := g.GraphQL(context.Background(), &data, `query { viewer { login } repository(owner: "octocat", name: "nope") { name } }`, nil)
+	e, ok := err.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Expected an *ErrorResponse, got %v", err)
+	}
+	if data.Viewer.Login != "octocat" || data.Repository != nil {
+		t.Errorf("Expected the partial data to be unmarshalled, got %+v", data)
+	}
+	if len(e.Errors) != 2 || e.Errors[0].Code != "NOT_FOUND" || e.Errors[1].Field != "repository.issues.0" || e.Errors[1].Message != "Resource not accessible by integration" {
+		t.Errorf("Unexpected errors %+v", e.Errors)
+	}
+	if e.Message != "Could not resolve to a Repository with the name 'octocat/nope'. (and 1 more GraphQL errors)" {
+		t.Errorf("Unexpected message %q", e.Message)
+	}
+	if !IsNotFound(err) {
+		t.Error("Expected the error to be a not found error")
+	}
+}
+
+func TestGraphQLPages(t *testing.T) {
+	pages := map[string]string{
+		"":             `{"nodes": [{"name": "a"}, {"name": "b"}], "pageInfo": {"hasNextPage": true, "endCursor": "Y3Vyc29yOjI="}}`,
+		"Y3Vyc29yOjI=": `{"nodes": [{"name": "c"}], "pageInfo": {"hasNextPage": false, "endCursor": "Y3Vyc29yOjM="}}`,
+	}
+	g, queries := newGraphQLServer(t, func(query string, variables map[string]interface{}) string {
+		cursor, _ := variables["cursor"].(string)
+		return fmt.Sprintf(`{"data": {"viewer": {"repositories": %s}}}`, pages[cursor])
+	})
+
+	var q struct {
+		Viewer struct {
+			Repositories struct {
+				Nodes    []struct{ Name string }
+				PageInfo PageInfo
+			} `graphql:"repositories(first: 2, after: $cursor)"`
+		}
+	}
+	var names []string
+	_, err := g.QueryPages(context.Background(), &q, nil, "cursor", func() (PageInfo, error) {
+		for _, repo := range q.Viewer.Repositories.Nodes {
+			names = append(names, repo.Name)
+		}
+		return q.Viewer.Repositories.PageInfo, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(names) != "[a b c]" {
+		t.Errorf("Unexpected names %v", names)
+	}
+	expected := "query($cursor:String){viewer{repositories(first: 2, after: $cursor){nodes{name},pageInfo{hasNextPage,endCursor,hasPreviousPage,startCursor}}}}"
+	if len(*queries) != 2 || (*queries)[0] != expected {
+		t.Errorf("Unexpected queries %q", *queries)
+	}
+}
+
+func TestGraphQLRateLimit(t *testing.T) {
+	g, queries := newGraphQLServer(t, func(query string, variables map[string]interface{}) string {
+		return `{"data": {}}`
+	})
+	g.graphqlRate = Rate{Limit: 5000, Remaining: 0, Reset: time.Now().Add(time.Hour)}
+	if _, err := g.GraphQL(context.Background(), nil, "query { viewer { login } }", nil); err != ErrRateLimitReached {
+		t.Errorf("Expected ErrRateLimitReached, got %v", err)
+	}
+	if len(*queries) != 0 {
+		t.Error("Expected no query to be sent")
+	}
+
+	// The REST rate limit running out does not stop GraphQL queries.
+	g.graphqlRate = Rate{}
+	g.RateLimit, g.RateLimitRemaining, g.RateLimitReset = 60, 0, time.Now().Add(time.Hour)
+	if _, err := g.GraphQL(context.Background(), nil, "query { viewer { login } }", nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsGraphQLMutation(t *testing.T) {
+	for query, expected := range map[string]bool{
+		"mutation { addStar(input: $input) { clientMutationId } }":                 true,
+		"mutation($input:AddStarInput!){addStar(input: $input){clientMutationId}}": true,
+		"# Stars a repository.\n  mutation AddStar { addStar }":                    true,
+		"query { viewer { login } }":                                               false,
+		"{ viewer { login } }":                                                     false,
+		"query mutations { viewer { login } }":                                     false,
+		"mutationsQuery { viewer { login } }":                                      false,
+	} {
+		if isGraphQLMutation(query) != expected {
+			t.Errorf("%q: expected %t", query, expected)
+		}
+	}
+}
+
+func TestGraphQLUrl(t *testing.T) {
+	tests := []struct {
+		base, url string
+	}{
+		{"https://api.github.com", "https://api.github.com/graphql"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/graphql"},
+	}
+	for _, test := range tests {
+		g, err := New(WithBaseUrl(test.base, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := g.graphqlUrl(); u != test.url {
+			t.Errorf("%s: expected %s, got %s", test.base, test.url, u)
+		}
+	}
+}
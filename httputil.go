@@ -15,10 +15,23 @@ type httpLink struct {
 	Rel string
 }
 
+// Parses the pagination links out of the response's Link header, as described
+// here: http://developer.github.com/v3/#pagination
+//
+// Links that cannot be parsed (and any other kind of link) are skipped, so a
+// response without a Link header has no links at all.
 func parseLinkHeader(response *http.Response) (links []*httpLink) {
-	locations := strings.Split(response.Header.Get("Link"), ",")
+	header := response.Header.Get("Link")
+	if header == "" {
+		return
+	}
+
+	locations := strings.Split(header, ",")
 	for _, v := range locations {
 		matches := linkRegex.FindStringSubmatch(strings.Trim(v, " "))
+		if matches == nil {
+			continue
+		}
 		link := &httpLink{Url: matches[1], Rel: matches[2]}
 		links = append(links, link)
 	}
@@ -1,6 +1,7 @@
 package gothub
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"testing"
@@ -11,7 +12,7 @@ var (
 )
 
 func TestParseLinkHeader(t *testing.T) {
-	response, err := http.Get(testUrl)
+	response, err := replaySession(t).httpGet(context.Background(), testUrl, nil)
 	if err != nil {
 		t.Fatal(err)
 		return
@@ -36,3 +37,21 @@ func TestParseLinkHeader(t *testing.T) {
 	}
 	return
 }
+
+func TestParseLinkHeaderSkipsMalformedLinks(t *testing.T) {
+	response := &http.Response{Header: make(http.Header)}
+	response.Header.Set("Link", `<https://api.github.com/user/repos?page=2>; rel="next", garbage, , <https://api.github.com/user/repos?page=5>; rel="last", <https://api.github.com/hub>; rel="hub"`)
+
+	links := parseLinkHeader(response)
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %+v", links)
+	}
+	if links[0].Rel != "next" || links[1].Rel != "last" {
+		t.Errorf("Unexpected links %+v, %+v", links[0], links[1])
+	}
+
+	response.Header.Del("Link")
+	if links := parseLinkHeader(response); len(links) != 0 {
+		t.Errorf("Expected no links, got %+v", links)
+	}
+}
@@ -0,0 +1,275 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// How each of the RFC 6570 operators expands its variables: what comes before
+// the first one, what goes between them, whether they are named ("name=value"),
+// what follows the name of an empty value, and whether reserved characters
+// are left unencoded.
+type templateOperator struct {
+	first         string
+	sep           string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	0:   {"", ",", false, "", false},
+	'+': {"", ",", false, "", true},
+	'#': {"#", ",", false, "", true},
+	'.': {".", ".", false, "", false},
+	'/': {"/", "/", false, "", false},
+	';': {";", ";", true, "", false},
+	'?': {"?", "&", true, "=", false},
+	'&': {"&", "&", true, "=", false},
+}
+
+/*
+Expands a URI template, as described by RFC 6570, with the given values. The
+hypermedia fields of the API's resources (the ones ending in Url) are often
+templates, such as a user's FollowingUrl:
+
+	u, err := gothub.ExpandUrlTemplate(user.FollowingUrl, map[string]interface{}{
+	    "other_user": "octocat",
+	})
+
+Values may be strings, lists of strings ([]string), maps of strings
+(map[string]string), or anything fmt.Sprint can turn into a string. Variables
+without a value, including empty lists and maps, are left out.
+*/
+func ExpandUrlTemplate(template string, values map[string]interface{}) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		literal := template
+		if start >= 0 {
+			literal = template[:start]
+		}
+		if strings.IndexByte(literal, '}') >= 0 {
+			return "", errors.New(fmt.Sprintf("gothub: unopened expression in URI template %q", template))
+		}
+		b.WriteString(literal)
+		if start < 0 {
+			return b.String(), nil
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", errors.New(fmt.Sprintf("gothub: unclosed expression in URI template %q", template))
+		}
+		if err := expandExpression(&b, template[start+1:start+end], values); err != nil {
+			return "", err
+		}
+		template = template[start+end+1:]
+	}
+}
+
+// Expands a single expression of a URI template, without its braces.
+func expandExpression(b *strings.Builder, expr string, values map[string]interface{}) error {
+	var op templateOperator
+	var ok bool
+	if expr != "" {
+		op, ok = templateOperators[expr[0]]
+	}
+	if ok {
+		expr = expr[1:]
+	} else if expr == "" || strings.ContainsRune("=,!@|", rune(expr[0])) {
+		return errors.New(fmt.Sprintf("gothub: invalid expression {%s} in URI template", expr))
+	} else {
+		op = templateOperators[0]
+	}
+
+	first := true
+	for _, spec := range strings.Split(expr, ",") {
+		name, explode, prefix, err := parseVarspec(spec)
+		if err != nil {
+			return err
+		}
+
+		var expanded string
+		switch v := values[name].(type) {
+		case nil:
+			continue
+		case []string:
+			if prefix > 0 {
+				return errors.New(fmt.Sprintf("gothub: prefix of list variable %q in URI template", name))
+			} else if len(v) == 0 {
+				continue
+			}
+			expanded = op.expandList(name, v, explode)
+		case map[string]string:
+			if prefix > 0 {
+				return errors.New(fmt.Sprintf("gothub: prefix of map variable %q in URI template", name))
+			} else if len(v) == 0 {
+				continue
+			}
+			expanded = op.expandMap(name, v, explode)
+		default:
+			expanded = op.expandString(name, fmt.Sprint(v), prefix)
+		}
+
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+		b.WriteString(expanded)
+	}
+	return nil
+}
+
+// Splits a variable specification into its name, and its explode or prefix
+// modifier.
+func parseVarspec(spec string) (name string, explode bool, prefix int, err error) {
+	name = spec
+	if strings.HasSuffix(spec, "*") {
+		name, explode = strings.TrimSuffix(spec, "*"), true
+	} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name = spec[:i]
+		prefix, err = strconv.Atoi(spec[i+1:])
+		if err != nil || prefix < 1 || prefix > 9999 {
+			return "", false, 0, errors.New(fmt.Sprintf("gothub: invalid prefix in URI template variable %q", spec))
+		}
+	}
+	if name == "" {
+		return "", false, 0, errors.New("gothub: empty URI template variable")
+	}
+	return name, explode, prefix, nil
+}
+
+func (op templateOperator) expandString(name, value string, prefix int) string {
+	if prefix > 0 && utf8.RuneCountInString(value) > prefix {
+		value = string([]rune(value)[:prefix])
+	}
+	if !op.named {
+		return op.encode(value)
+	}
+	if value == "" {
+		return name + op.ifEmpty
+	}
+	return name + "=" + op.encode(value)
+}
+
+func (op templateOperator) expandList(name string, list []string, explode bool) string {
+	parts := make([]string, len(list))
+	for i, item := range list {
+		if explode && op.named {
+			parts[i] = op.expandString(name, item, 0)
+		} else {
+			parts[i] = op.encode(item)
+		}
+	}
+	if explode {
+		return strings.Join(parts, op.sep)
+	}
+	if op.named {
+		return name + "=" + strings.Join(parts, ",")
+	}
+	return strings.Join(parts, ",")
+}
+
+func (op templateOperator) expandMap(name string, m map[string]string, explode bool) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		switch {
+		case !explode:
+			parts = append(parts, op.encode(k), op.encode(m[k]))
+		case op.named && m[k] == "":
+			parts = append(parts, op.encode(k)+op.ifEmpty)
+		default:
+			parts = append(parts, op.encode(k)+"="+op.encode(m[k]))
+		}
+	}
+	if explode {
+		return strings.Join(parts, op.sep)
+	}
+	if op.named {
+		return name + "=" + strings.Join(parts, ",")
+	}
+	return strings.Join(parts, ",")
+}
+
+// Percent-encodes everything but the unreserved characters and, for the
+// operators that allow them, the reserved characters and existing
+// percent-encoded triplets.
+func (op templateOperator) encode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', strings.IndexByte("-._~", c) >= 0:
+			b.WriteByte(c)
+		case op.allowReserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0:
+			b.WriteByte(c)
+		case op.allowReserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+/*
+Expands a hypermedia link (one of the Url fields of the API's resources) with
+ExpandUrlTemplate, then fetches it like DoRequest, unmarshalling the JSON
+response into v:
+
+	var commits []map[string]interface{}
+	_, err := g.FollowLink(ctx, &commits, repo.CommitsUrl, map[string]interface{}{
+	    "sha": "main",
+	})
+
+Links are only followed to the session's API and uploads hosts, so that its
+credentials are not sent anywhere else.
+*/
+func (g *GitHub) FollowLink(ctx context.Context, v interface{}, link string, values map[string]interface{}) (*Response, error) {
+	if link == "" {
+		return nil, errors.New("gothub: empty link")
+	}
+	expanded, err := ExpandUrlTemplate(link, values)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(expanded)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsAbs() && !g.apiHost(u) {
+		return nil, errors.New(fmt.Sprintf("gothub: %q is not a link to the API", expanded))
+	}
+	return g.DoRequest(ctx, v, "GET", expanded, nil, nil)
+}
+
+// Reports whether the URL is on the session's API or uploads host.
+func (g *GitHub) apiHost(u *url.URL) bool {
+	for _, base := range []string{g.apiUrl(""), g.UploadUrl} {
+		if b, err := url.Parse(base); err == nil && strings.EqualFold(b.Scheme, u.Scheme) && strings.EqualFold(b.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package gothub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// The examples of RFC 6570, section 3.2.
+func TestExpandUrlTemplate(t *testing.T) {
+	values := map[string]interface{}{
+		"count":      []string{"one", "two", "three"},
+		"dom":        []string{"example", "com"},
+		"dub":        "me/too",
+		"hello":      "Hello World!",
+		"half":       "50%",
+		"var":        "value",
+		"who":        "fred",
+		"base":       "http://example.com/home/",
+		"path":       "/foo/bar",
+		"list":       []string{"red", "green", "blue"},
+		"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"v":          6,
+		"x":          1024,
+		"y":          768,
+		"empty":      "",
+		"empty_keys": map[string]string{},
+	}
+
+	tests := []struct {
+		template, expanded string
+	}{
+		{"{count}", "one,two,three"},
+		{"{count*}", "one,two,three"},
+		{"{/count}", "/one,two,three"},
+		{"{/count*}", "/one/two/three"},
+		{"{;count}", ";count=one,two,three"},
+		{"{;count*}", ";count=one;count=two;count=three"},
+		{"{?count}", "?count=one,two,three"},
+		{"{?count*}", "?count=one&count=two&count=three"},
+		{"{&count*}", "&count=one&count=two&count=three"},
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{half}", "50%25"},
+		{"O{empty}X", "OX"},
+		{"O{undef}X", "OX"},
+		{"{x,y}", "1024,768"},
+		{"{x,hello,y}", "1024,Hello%20World%21,768"},
+		{"?{x,empty}", "?1024,"},
+		{"?{x,undef}", "?1024"},
+		{"{var:3}", "val"},
+		{"{var:30}", "value"},
+		{"{list}", "red,green,blue"},
+		{"{keys}", "comma,%2C,dot,.,semi,%3B"},
+		{"{keys*}", "comma=%2C,dot=.,semi=%3B"},
+		{"{+var}", "value"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+half}", "50%25"},
+		{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{"{+base}index", "http://example.com/home/index"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"here?ref={+path}", "here?ref=/foo/bar"},
+		{"{+path:6}/here", "/foo/b/here"},
+		{"{#var}", "#value"},
+		{"{#hello}", "#Hello%20World!"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{#keys*}", "#comma=,,dot=.,semi=;"},
+		{"X{.var}", "X.value"},
+		{"X{.x,y}", "X.1024.768"},
+		{"www{.dom*}", "www.example.com"},
+		{"X{.list*}", "X.red.green.blue"},
+		{"X{.empty_keys}", "X"},
+		{"{/var,x}/here", "/value/1024/here"},
+		{"{/var:1,var}", "/v/value"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{/keys*}", "/comma=%2C/dot=./semi=%3B"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{;v,empty,who}", ";v=6;empty;who=fred"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{;keys*}", ";comma=%2C;dot=.;semi=%3B"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"{?x,y,undef}", "?x=1024&y=768"},
+		{"{?var:3}", "?var=val"},
+		{"{?list}", "?list=red,green,blue"},
+		{"{?keys}", "?keys=comma,%2C,dot,.,semi,%3B"},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{&var:3}", "&var=val"},
+		{"{dub}", "me%2Ftoo"},
+		{"https://api.github.com/users/octocat/following{/other_user}", "https://api.github.com/users/octocat/following"},
+	}
+	for _, test := range tests {
+		expanded, err := ExpandUrlTemplate(test.template, values)
+		if err != nil {
+			t.Errorf("%s: %s", test.template, err)
+		} else if expanded != test.expanded {
+			t.Errorf("%s: expected %q, got %q", test.template, test.expanded, expanded)
+		}
+	}
+
+	for _, template := range []string{"{", "}", "a}{b}", "{}", "{=var}", "{var:0}", "{var:x}", "{list:2}", "{,}"} {
+		if _, err := ExpandUrlTemplate(template, values); err == nil {
+			t.Errorf("%s: expected an error", template)
+		}
+	}
+}
+
+func TestFollowLink(t *testing.T) {
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("Expected the session's credentials, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		switch r.URL.RequestURI() {
+		case "/repos/octocat/hello-world":
+			fmt.Fprintf(w, `{"name": "hello-world", "commits_url": "http://%[1]s/repos/octocat/hello-world/commits{/sha}", "contents_url": "http://%[1]s/repos/octocat/hello-world/contents/{+path}"}`, r.Host)
+		case "/repos/octocat/hello-world/commits/main":
+			fmt.Fprint(w, `{"sha": "6dcb09b5b57875f334f61aebed695e2e4193db5e"}`)
+		case "/repos/octocat/hello-world/contents/docs/README.md":
+			fmt.Fprint(w, `{"path": "docs/README.md"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, WithToken("secret"))
+	ctx := context.Background()
+
+	var repo Repository
+	if _, err := g.DoContext(ctx, &repo, "GET", "repos/octocat/hello-world"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.CommitsUrl == "" || repo.ContentsUrl == "" {
+		t.Fatalf("Expected the commits and contents links, got %q and %q", repo.CommitsUrl, repo.ContentsUrl)
+	}
+
+	var commit struct{ Sha string }
+	if _, err := g.FollowLink(ctx, &commit, repo.CommitsUrl, map[string]interface{}{"sha": "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if commit.Sha != "6dcb09b5b57875f334f61aebed695e2e4193db5e" {
+		t.Errorf("Unexpected commit %+v", commit)
+	}
+
+	var contents map[string]string
+	resp, err := g.FollowLink(ctx, &contents, repo.ContentsUrl, map[string]interface{}{"path": "docs/README.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents["path"] != "docs/README.md" || resp.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected contents %+v", contents)
+	}
+
+	if _, err := g.FollowLink(ctx, nil, "https://example.com/steal{?token}", nil); err == nil {
+		t.Error("Expected a link to another host to be refused")
+	}
+	if _, err := g.FollowLink(ctx, nil, "", nil); err == nil {
+		t.Error("Expected an empty link to be refused")
+	}
+}
+
+func TestRepositoryLinkTags(t *testing.T) {
+	var repo Repository
+	data := `{"comments_url": "comments", "commits_url": "commits", "contents_url": "contents"}`
+	if err := json.Unmarshal([]byte(data), &repo); err != nil {
+		t.Fatal(err)
+	}
+	if repo.CommentsUrl != "comments" || repo.CommitsUrl != "commits" || repo.ContentsUrl != "contents" {
+		t.Errorf("Unexpected links %q, %q, %q", repo.CommentsUrl, repo.CommitsUrl, repo.ContentsUrl)
+	}
+}
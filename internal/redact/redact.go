@@ -0,0 +1,73 @@
+// Package redact knows which parts of the requests to, and responses from,
+// the GitHub API carry credentials, and blanks them out. It is shared by the
+// tracer and the replay recorder, so that they agree on what is a secret.
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// What credentials are replaced with.
+const Placeholder = "REDACTED"
+
+// The headers that carry credentials.
+var Headers = []string{"Authorization", "Proxy-Authorization", "X-GitHub-OTP", "Cookie", "Set-Cookie"}
+
+// The query parameters that carry credentials.
+var Params = []string{"access_token", "client_secret"}
+
+// The fields of JSON (and form) bodies that carry credentials, such as the
+// tokens handed out for installations and OAuth apps.
+var Fields = []string{"token", "access_token", "refresh_token", "client_secret"}
+
+var (
+	jsonField = regexp.MustCompile(`"(` + strings.Join(Fields, "|") + `)"(\s*:\s*)"(?:[^"\\]|\\.)*(?:"|$)`)
+	formField = regexp.MustCompile(`(^|&)(` + strings.Join(Fields, "|") + `)=[^&]*`)
+)
+
+// Returns a copy of the header with the credentials redacted.
+func Header(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range Headers {
+		if len(h.Values(name)) > 0 {
+			h.Set(name, Placeholder)
+		}
+	}
+	return h
+}
+
+// Returns the URL with the credentials in its user info and query redacted.
+// The user name is redacted along with the password, since tokens are often
+// sent as one.
+func Url(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User(Placeholder)
+	}
+
+	query := u.Query()
+	changed := false
+	for _, name := range Params {
+		if query.Has(name) {
+			query.Set(name, Placeholder)
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// Returns a copy of the body with the values of its credential fields
+// redacted. It works on the start of a body, too, which is not valid JSON.
+func Body(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	b = jsonField.ReplaceAll(b, []byte(`"$1"$2"`+Placeholder+`"`))
+	return formField.ReplaceAll(b, []byte("$1$2="+Placeholder))
+}
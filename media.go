@@ -0,0 +1,101 @@
+package gothub
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// The media types the API can respond with, as described here:
+// http://developer.github.com/v3/media/
+//
+// The JSON ones change how the Markdown bodies of issues, comments and the
+// like are represented; the others replace the JSON altogether, and are only
+// supported by some endpoints (such as file contents, commits and pull
+// requests). Pick one for a request with WithMediaType.
+const (
+	MediaTypeJson = "application/vnd.github.v3+json"
+
+	// Markdown bodies as they were written (the default), as plain text, as
+	// rendered HTML, or all three of them.
+	MediaTypeRawJson  = "application/vnd.github.v3.raw+json"
+	MediaTypeTextJson = "application/vnd.github.v3.text+json"
+	MediaTypeHtmlJson = "application/vnd.github.v3.html+json"
+	MediaTypeFullJson = "application/vnd.github.v3.full+json"
+
+	// The raw contents of a file (or blob), or its rendered HTML.
+	MediaTypeRaw  = "application/vnd.github.v3.raw"
+	MediaTypeHtml = "application/vnd.github.v3.html"
+
+	// A commit, comparison or pull request, as a diff or a patch.
+	MediaTypeDiff  = "application/vnd.github.v3.diff"
+	MediaTypePatch = "application/vnd.github.v3.patch"
+)
+
+type mediaTypeKey struct{}
+
+// Returns the media type that opts a request in to one of the API's previews,
+// given the preview's name, e.g. PreviewMediaType("mercy") for
+// "application/vnd.github.mercy-preview+json".
+func PreviewMediaType(name string) string {
+	return fmt.Sprintf("application/vnd.github.%s-preview+json", name)
+}
+
+// Returns a copy of ctx that makes the requests it is passed to ask for the
+// given media types (in the Accept header), rather than the API's default
+// JSON. Use one of the MediaType constants, along with any number of preview
+// media types:
+//
+//	ctx = gothub.WithMediaType(ctx, gothub.MediaTypeFullJson, gothub.PreviewMediaType("squirrel-girl"))
+//
+// Only the JSON media types work with the methods that decode their response;
+// for the others, use DoStream or DoString.
+func WithMediaType(ctx context.Context, mediaTypes ...string) context.Context {
+	return context.WithValue(ctx, mediaTypeKey{}, strings.Join(mediaTypes, ", "))
+}
+
+// Returns the Accept header for requests bound to ctx.
+func acceptHeader(ctx context.Context) string {
+	if accept, ok := ctx.Value(mediaTypeKey{}).(string); ok && accept != "" {
+		return accept
+	}
+	return AcceptHeader
+}
+
+// Like DoRequest, but hands back the body of the response for the caller to
+// read (and close), whatever its media type. Together with WithMediaType, this
+// gets at the raw contents of files, and the diffs and patches of commits and
+// pull requests:
+//
+//	ctx = gothub.WithMediaType(ctx, gothub.MediaTypeRaw)
+//	body, _, err := g.DoStream(ctx, "GET", "/repos/octocat/hello-world/contents/README", nil, nil)
+//
+// The body is nil whenever the error is not.
+func (g *GitHub) DoStream(ctx context.Context, method, uri string, query url.Values, body interface{}) (io.ReadCloser, *Response, error) {
+	response, err := g.do(ctx, method, uri, query, body)
+	if err != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+		return nil, newResponse(response), err
+	}
+	return response.Body, newResponse(response), nil
+}
+
+// Like DoStream, but reads the whole body of the response into a string.
+func (g *GitHub) DoString(ctx context.Context, method, uri string, query url.Values, body interface{}) (string, *Response, error) {
+	stream, resp, err := g.DoStream(ctx, method, uri, query, body)
+	if err != nil {
+		return "", resp, err
+	}
+	defer stream.Close()
+
+	b, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return "", resp, err
+	}
+	return string(b), resp, nil
+}
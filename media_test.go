@@ -0,0 +1,112 @@
+package gothub
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWithMediaType(t *testing.T) {
+	var accept string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		accept = r.Header.Get("Accept")
+		return jsonResponse(r, http.StatusOK, `{"login": "octocat"}`), nil
+	})
+	g, err := New(WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if accept != AcceptHeader {
+		t.Errorf("Expected the default Accept header, got %q", accept)
+	}
+
+	ctx := WithMediaType(context.Background(), MediaTypeFullJson, PreviewMediaType("squirrel-girl"))
+	if _, _, err := g.GetUserContext(ctx, "octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if accept != "application/vnd.github.v3.full+json, application/vnd.github.squirrel-girl-preview+json" {
+		t.Errorf("Unexpected Accept header %q", accept)
+	}
+}
+
+func TestDoStringDiff(t *testing.T) {
+	const diff = "diff --git a/README b/README\n"
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if accept := r.Header.Get("Accept"); accept != MediaTypeDiff {
+			t.Errorf("Unexpected Accept header %q", accept)
+		}
+		response := jsonResponse(r, http.StatusOK, diff)
+		response.Header.Set("Content-Type", "application/vnd.github.v3.diff; charset=utf-8")
+		return response, nil
+	})
+	g, err := New(WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithMediaType(context.Background(), MediaTypeDiff)
+	body, resp, err := g.DoString(ctx, "GET", "/repos/octocat/hello-world/pulls/1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != diff || resp.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	// The typed methods still insist on JSON.
+	if _, _, err := g.GetUserContext(ctx, "octocat"); err != ErrNoJSON {
+		t.Errorf("Expected ErrNoJSON, got %v", err)
+	}
+}
+
+func TestDoStreamRaw(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Query().Get("ref") != "main" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		response := jsonResponse(r, http.StatusOK, "Hello World")
+		response.Header.Set("Content-Type", "application/vnd.github.v3.raw")
+		return response, nil
+	})
+	g, err := New(WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithMediaType(context.Background(), MediaTypeRaw)
+	stream, _, err := g.DoStream(ctx, "GET", "/repos/octocat/hello-world/contents/README", url.Values{"ref": {"main"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	b, err := ioutil.ReadAll(stream)
+	if err != nil || string(b) != "Hello World" {
+		t.Errorf("Unexpected contents %q (%v)", b, err)
+	}
+}
+
+func TestDoStreamError(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(r, http.StatusNotFound, `{"message": "Not Found"}`), nil
+	})
+	g, err := New(WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, resp, err := g.DoStream(context.Background(), "GET", "/repos/octocat/nope/contents/README", nil, nil)
+	if stream != nil || !IsNotFound(err) {
+		t.Errorf("Expected a 404 and no body, got %v", err)
+	}
+	if resp == nil || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
@@ -0,0 +1,343 @@
+package gothub
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Is told about every request a session sends (every attempt, when requests
+// are retried), for exporting counters, histograms and gauges to a metrics
+// system such as Prometheus. Set one up with WithMetrics.
+//
+// ObserveRequest is called from the goroutine that made the request, so it
+// must be safe for concurrent use if the session is shared.
+type Metrics interface {
+	ObserveRequest(ctx context.Context, r *RequestMetrics)
+}
+
+// Adapts an ordinary function to the Metrics interface.
+type MetricsFunc func(ctx context.Context, r *RequestMetrics)
+
+func (f MetricsFunc) ObserveRequest(ctx context.Context, r *RequestMetrics) {
+	f(ctx, r)
+}
+
+// Describes a request that a session sent, and what came of it.
+type RequestMetrics struct {
+	// The method of the request, and the endpoint it was sent to, with the
+	// names of users, repositories and the like replaced by placeholders
+	// (e.g. "/users/{user}"), to keep the number of distinct routes down.
+	Method string
+	Route  string
+
+	// The status code of the response; zero when the request could not be
+	// sent.
+	StatusCode int
+
+	// The rate limit the response reported; zero if it did not.
+	Rate Rate
+
+	// How long it took for the response to come back, how long the session's
+	// Throttle held the request back before it was sent, and which attempt
+	// at the request this was, starting at 1.
+	Latency      time.Duration
+	ThrottleWait time.Duration
+	Attempt      int
+
+	// Why the request failed, if it did.
+	Err error
+}
+
+// Builds the RequestMetrics for a request, and hands them to the session's
+// metrics.
+func (g *GitHub) observe(req *http.Request, response *http.Response, err error, latency, throttleWait time.Duration, attempt int) {
+	r := &RequestMetrics{
+		Method:       req.Method,
+		Route:        g.route(req.URL),
+		Latency:      latency,
+		ThrottleWait: throttleWait,
+		Attempt:      attempt,
+		Err:          err,
+	}
+	if response != nil {
+		r.StatusCode = response.StatusCode
+		r.Rate, _ = parseRate(response.Header)
+	}
+	g.metrics.ObserveRequest(req.Context(), r)
+}
+
+// Matches the segments of a path that identify a single resource, rather than
+// name an endpoint: numeric IDs and commit SHAs.
+var (
+	idSegment  = regexp.MustCompile(`^[0-9]+$`)
+	shaSegment = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+)
+
+// The placeholders for the segments that follow these segments of a
+// repository's path; "*" stands for the rest of the path.
+var repositoryRoutes = map[string][]string{
+	"branches":      {"{branch}"},
+	"collaborators": {"{username}"},
+	"commits":       {"{ref}"},
+	"compare":       {"{basehead}"},
+	"contents":      {"{path}", "*"},
+	"labels":        {"{name}"},
+	"readme":        {"{dir}", "*"},
+	"refs":          {"{ref}", "*"},
+	"tags":          {"{tag}"},
+	"tarball":       {"{ref}", "*"},
+	"zipball":       {"{ref}", "*"},
+}
+
+// Returns the route of the API endpoint at the given path, relative to the
+// session's base URL, e.g. "/repos/{owner}/{repo}/issues/{id}" for
+// "/repos/octocat/hello-world/issues/1347".
+func routeTemplate(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) == 1 && segments[0] == "" {
+		return "/"
+	}
+
+	replace := func(i int, placeholder string) {
+		if i < len(segments) {
+			segments[i] = placeholder
+		}
+	}
+	switch segments[0] {
+	case "users":
+		replace(1, "{user}")
+	case "orgs":
+		replace(1, "{org}")
+	case "gists":
+		replace(1, "{gist_id}")
+	case "user":
+		if len(segments) > 2 && segments[1] == "following" {
+			replace(2, "{user}")
+		}
+	case "legacy":
+		// The search terms (and options) of the legacy search API.
+		if len(segments) > 3 {
+			segments = append(segments[:3], "{query}")
+		}
+	case "repos":
+		replace(1, "{owner}")
+		replace(2, "{repo}")
+		for i := 3; i < len(segments); i++ {
+			placeholders, ok := repositoryRoutes[segments[i]]
+			if !ok || i+1 >= len(segments) {
+				continue
+			}
+			if len(placeholders) > 1 && placeholders[1] == "*" {
+				segments = append(segments[:i+1], placeholders[0])
+				break
+			}
+			if !idSegment.MatchString(segments[i+1]) {
+				segments[i+1] = placeholders[0]
+			}
+			i++
+		}
+	}
+
+	for i, segment := range segments {
+		switch {
+		case idSegment.MatchString(segment):
+			segments[i] = "{id}"
+		case shaSegment.MatchString(segment):
+			segments[i] = "{sha}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Returns the route of the endpoint at the URL, on the session's API or
+// uploads host.
+func (g *GitHub) route(u *url.URL) string {
+	path := u.Path
+	for _, base := range []string{g.apiUrl(""), g.UploadUrl} {
+		if b, err := url.Parse(base); err == nil && strings.EqualFold(b.Host, u.Host) {
+			if prefix := strings.TrimRight(b.Path, "/"); strings.HasPrefix(path, prefix+"/") {
+				path = strings.TrimPrefix(path, prefix)
+				break
+			}
+		}
+	}
+	return routeTemplate(path)
+}
+
+// The upper bounds of the buckets of the latency histograms kept by
+// MemoryMetrics, unless it is given others; the same as Prometheus's default
+// buckets.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// A histogram of request latencies, the way Prometheus keeps them.
+type Histogram struct {
+	// The upper bounds of the buckets, and how many observations were at
+	// most as long as each of them (so the counts add up as they go).
+	Buckets []time.Duration
+	Counts  []int
+
+	// How many observations there were, and how long they were altogether.
+	Count int
+	Sum   time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	for i, bound := range h.Buckets {
+		if d <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += d
+}
+
+type routeMetrics struct {
+	requests int
+	errors   map[int]int
+	latency  Histogram
+}
+
+// Keeps the metrics of the requests it is told about in memory, the way
+// Prometheus would: counts of requests and of errors by route, latency
+// histograms by route, and the remaining quota. It is meant for tests, and as
+// an example of what to export:
+//
+//	metrics := gothub.NewMemoryMetrics()
+//	g, err := gothub.New(gothub.WithMetrics(metrics))
+//	...
+//	if metrics.Requests("GET", "/users/{user}") != 1 {
+//	    t.Error("Expected to get the user")
+//	}
+//
+// It is safe for concurrent use.
+type MemoryMetrics struct {
+	buckets []time.Duration
+
+	mu           sync.Mutex
+	routes       map[string]*routeMetrics
+	rate         Rate
+	throttled    int
+	throttleWait time.Duration
+}
+
+// Creates an empty MemoryMetrics, whose latency histograms have the given
+// buckets, or DefaultLatencyBuckets if there are none.
+func NewMemoryMetrics(buckets ...time.Duration) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &MemoryMetrics{buckets: buckets, routes: make(map[string]*routeMetrics)}
+}
+
+func (m *MemoryMetrics) ObserveRequest(ctx context.Context, r *RequestMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := r.Method + " " + r.Route
+	rm, ok := m.routes[key]
+	if !ok {
+		rm = &routeMetrics{
+			errors:  make(map[int]int),
+			latency: Histogram{Buckets: m.buckets, Counts: make([]int, len(m.buckets))},
+		}
+		m.routes[key] = rm
+	}
+
+	rm.requests++
+	if r.Err != nil || r.StatusCode >= 400 {
+		rm.errors[r.StatusCode]++
+	}
+	rm.latency.observe(r.Latency)
+	if r.Rate.Limit > 0 {
+		m.rate = r.Rate
+	}
+	if r.ThrottleWait > 0 {
+		m.throttled++
+		m.throttleWait += r.ThrottleWait
+	}
+}
+
+// Returns the routes that requests were sent to, as "<method> <route>", in
+// order.
+func (m *MemoryMetrics) Routes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]string, 0, len(m.routes))
+	for key := range m.routes {
+		routes = append(routes, key)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// Returns how many requests were sent to the route.
+func (m *MemoryMetrics) Requests(method, route string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rm, ok := m.routes[method+" "+route]; ok {
+		return rm.requests
+	}
+	return 0
+}
+
+// Returns how many requests to the route failed with the given status code;
+// a status of zero counts the requests that could not be sent at all.
+func (m *MemoryMetrics) Errors(method, route string, status int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rm, ok := m.routes[method+" "+route]; ok {
+		return rm.errors[status]
+	}
+	return 0
+}
+
+// Returns the latency histogram of the route.
+func (m *MemoryMetrics) Latency(method, route string) Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := Histogram{Buckets: m.buckets, Counts: make([]int, len(m.buckets))}
+	if rm, ok := m.routes[method+" "+route]; ok {
+		h.Count, h.Sum = rm.latency.Count, rm.latency.Sum
+		copy(h.Counts, rm.latency.Counts)
+	}
+	return h
+}
+
+// Returns the rate limit reported by the latest response that had one.
+func (m *MemoryMetrics) Quota() Rate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rate
+}
+
+// Returns how many requests were held back by the session's Throttle, and for
+// how long altogether.
+func (m *MemoryMetrics) Throttled() (requests int, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.throttled, m.throttleWait
+}
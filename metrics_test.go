@@ -0,0 +1,61 @@
+package gothub
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		path, route string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/user", "/user"},
+		{"/users/octocat", "/users/{user}"},
+		{"/users/octocat/followers", "/users/{user}/followers"},
+		{"/user/following/octocat", "/user/following/{user}"},
+		{"/user/keys/42", "/user/keys/{id}"},
+		{"/orgs/github", "/orgs/{org}"},
+		{"/orgs/github/teams/7/members", "/orgs/{org}/teams/{id}/members"},
+		{"/repos/octocat/hello-world", "/repos/{owner}/{repo}"},
+		{"/repos/octocat/hello-world/issues/1347", "/repos/{owner}/{repo}/issues/{id}"},
+		{"/repos/octocat/hello-world/issues/1347/comments", "/repos/{owner}/{repo}/issues/{id}/comments"},
+		{"/repos/octocat/hello-world/contents/docs/README.md", "/repos/{owner}/{repo}/contents/{path}"},
+		{"/repos/octocat/hello-world/commits/main", "/repos/{owner}/{repo}/commits/{ref}"},
+		{"/repos/octocat/hello-world/commits/6dcb09b5b57875f334f61aebed695e2e4193db5e/status", "/repos/{owner}/{repo}/commits/{ref}/status"},
+		{"/repos/octocat/hello-world/git/refs/heads/main", "/repos/{owner}/{repo}/git/refs/{ref}"},
+		{"/repos/octocat/hello-world/git/trees/6dcb09b5b57875f334f61aebed695e2e4193db5e", "/repos/{owner}/{repo}/git/trees/{sha}"},
+		{"/legacy/user/search/the+octocat/2", "/legacy/user/search/{query}"},
+		{"/gists/aa5a315d61ae9438b18d", "/gists/{gist_id}"},
+	}
+	for _, test := range tests {
+		if route := routeTemplate(test.path); route != test.route {
+			t.Errorf("%q: expected %q, got %q", test.path, test.route, route)
+		}
+	}
+
+	g, err := New(WithBaseUrl("https://ghe.example.com/api/v3", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"https://ghe.example.com/api/v3/users/octocat", "https://ghe.example.com/api/uploads/users/octocat"} {
+		parsed, _ := url.Parse(u)
+		if route := g.route(parsed); route != "/users/{user}" {
+			t.Errorf("%s: expected the base URL to be left out, got %q", u, route)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	metrics := NewMemoryMetrics(time.Second, 100*time.Millisecond)
+	for _, latency := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond, 5 * time.Second} {
+		metrics.ObserveRequest(context.Background(), &RequestMetrics{Method: "GET", Route: "/", Latency: latency})
+	}
+	h := metrics.Latency("GET", "/")
+	if h.Buckets[0] != 100*time.Millisecond || h.Counts[0] != 1 || h.Counts[1] != 2 || h.Count != 3 {
+		t.Errorf("Unexpected histogram %+v", h)
+	}
+}
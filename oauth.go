@@ -0,0 +1,314 @@
+package gothub
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The web host that serves GitHub's OAuth endpoints.
+const GitHubWebUrl string = "https://github.com"
+
+var (
+	ErrOAuthStateMismatch = errors.New("OAuth state does not match; possible CSRF attempt")
+	ErrDeviceCodeExpired  = errors.New("Device code expired before the user authorized it")
+)
+
+// Holds the settings of an OAuth app, for signing users in with GitHub, as
+// described here:
+// https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps
+type OAuthApp struct {
+	ClientId     string
+	ClientSecret string
+
+	// Where GitHub sends users back to after they authorize the app. When
+	// empty, the callback URL from the app's settings is used.
+	RedirectUrl string
+
+	// The scopes to ask the user for.
+	Scopes []string
+
+	// The root of the GitHub web site serving the OAuth endpoints. Defaults
+	// to GitHubWebUrl; for GitHub Enterprise Server, it is the root of the
+	// install, e.g. "https://ghe.example.com".
+	WebUrl string
+
+	// The HTTP client used to talk to the OAuth endpoints. Defaults to
+	// http.DefaultClient.
+	HttpClient *http.Client
+
+	// The unit that device flow polling intervals and expiry times are
+	// counted in. Defaults to a second.
+	pollUnit time.Duration
+}
+
+// An access token granted to an OAuth app.
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+
+	// Only set for apps that opted in to expiring user tokens.
+	ExpiresIn             int    `json:"expires_in,omitempty"`
+	RefreshToken          string `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in,omitempty"`
+}
+
+// Returns the scopes that the user actually granted, which may differ from the
+// ones that were asked for.
+func (t *OAuthToken) Scopes() []string {
+	return parseScopes(t.Scope)
+}
+
+// An error reported by one of GitHub's OAuth endpoints.
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	Uri         string `json:"error_uri"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("OAuth error: %s", e.Code)
+	}
+	return fmt.Sprintf("OAuth error: %s: %s", e.Code, e.Description)
+}
+
+// Generates a random value for the state parameter of the web application
+// flow. Keep it somewhere tied to the user's browser session (e.g. a cookie)
+// and hand it back to HandleCallback.
+func NewOAuthState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Returns the URL to send users to, so they can authorize the app.
+func (a *OAuthApp) AuthorizeUrl(state string) string {
+	v := url.Values{}
+	v.Set("client_id", a.ClientId)
+	v.Set("state", state)
+	if a.RedirectUrl != "" {
+		v.Set("redirect_uri", a.RedirectUrl)
+	}
+	if len(a.Scopes) > 0 {
+		v.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	return fmt.Sprintf("%s?%s", a.webUrl("/login/oauth/authorize"), v.Encode())
+}
+
+// Handles the request GitHub redirected the user back to: makes sure its state
+// matches the one given to AuthorizeUrl, then exchanges its code for a token.
+func (a *OAuthApp) HandleCallback(ctx context.Context, r *http.Request, state string) (*OAuthToken, error) {
+	query := r.URL.Query()
+	if code := query.Get("error"); code != "" {
+		return nil, &OAuthError{Code: code, Description: query.Get("error_description"), Uri: query.Get("error_uri")}
+	}
+
+	got := query.Get("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(got), []byte(state)) != 1 {
+		return nil, ErrOAuthStateMismatch
+	}
+
+	return a.Exchange(ctx, query.Get("code"))
+}
+
+// Exchanges the code GitHub handed back to the redirect URL for an access
+// token.
+func (a *OAuthApp) Exchange(ctx context.Context, code string) (*OAuthToken, error) {
+	v := url.Values{}
+	v.Set("client_id", a.ClientId)
+	v.Set("client_secret", a.ClientSecret)
+	v.Set("code", code)
+	if a.RedirectUrl != "" {
+		v.Set("redirect_uri", a.RedirectUrl)
+	}
+
+	var token OAuthToken
+	if err := a.post(ctx, "/login/oauth/access_token", v, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Creates a session that is authenticated with the given token. The session
+// can be configured further with the same options as New; for GitHub
+// Enterprise Server, that includes WithBaseUrl.
+func (a *OAuthApp) Session(token *OAuthToken, opts ...Option) (*GitHub, error) {
+	if token == nil || token.AccessToken == "" {
+		return nil, errors.New("No OAuth access token")
+	}
+
+	// The caller's options go after the app's own, so they can override
+	// them, but the token goes last. A new slice keeps the caller's from
+	// being written to.
+	all := make([]Option, 0, len(opts)+2)
+	if a.HttpClient != nil {
+		all = append(all, WithHttpClient(a.HttpClient))
+	}
+	all = append(all, opts...)
+	all = append(all, WithBearerToken(token.AccessToken))
+
+	g, err := New(all...)
+	if err != nil {
+		return nil, err
+	}
+	g.Scopes = token.Scopes()
+	return g, nil
+}
+
+// The codes handed out at the start of the device flow, as described here:
+// https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps#device-flow
+//
+// Show the UserCode to the user, and ask them to enter it at the
+// VerificationUri.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationUri string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+
+	// When the device code was issued, so we know when it expires.
+	issued time.Time
+}
+
+// Starts the device flow, for apps (such as CLIs) that have no browser to
+// redirect.
+func (a *OAuthApp) RequestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	v := url.Values{}
+	v.Set("client_id", a.ClientId)
+	if len(a.Scopes) > 0 {
+		v.Set("scope", strings.Join(a.Scopes, " "))
+	}
+
+	var code DeviceCode
+	if err := a.post(ctx, "/login/device/code", v, &code); err != nil {
+		return nil, err
+	}
+	code.issued = time.Now()
+	return &code, nil
+}
+
+// Waits for the user to enter the device code, then returns the access token.
+//
+// The token endpoint is polled no more often than GitHub allows; polling stops
+// when ctx is done, when the device code expires, or when the user denies the
+// app access.
+func (a *OAuthApp) PollDeviceToken(ctx context.Context, code *DeviceCode) (*OAuthToken, error) {
+	interval := code.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+	unit := a.pollUnit
+	if unit <= 0 {
+		unit = time.Second
+	}
+
+	var expires <-chan time.Time
+	if code.ExpiresIn > 0 {
+		issued := code.issued
+		if issued.IsZero() {
+			issued = time.Now()
+		}
+		timer := time.NewTimer(time.Until(issued.Add(time.Duration(code.ExpiresIn) * unit)))
+		defer timer.Stop()
+		expires = timer.C
+	}
+
+	v := url.Values{}
+	v.Set("client_id", a.ClientId)
+	v.Set("device_code", code.DeviceCode)
+	v.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-expires:
+			return nil, ErrDeviceCodeExpired
+		case <-time.After(time.Duration(interval) * unit):
+		}
+
+		var token OAuthToken
+		err := a.post(ctx, "/login/oauth/access_token", v, &token)
+		if err == nil {
+			return &token, nil
+		}
+
+		var oauthErr *OAuthError
+		if !errors.As(err, &oauthErr) {
+			return nil, err
+		}
+		switch oauthErr.Code {
+		case "authorization_pending":
+			// The user has not entered the code yet.
+		case "slow_down":
+			interval += 5
+		case "expired_token":
+			return nil, ErrDeviceCodeExpired
+		default:
+			return nil, err
+		}
+	}
+}
+
+// Returns the absolute URL of the given OAuth endpoint.
+func (a *OAuthApp) webUrl(uri string) string {
+	base := a.WebUrl
+	if base == "" {
+		base = GitHubWebUrl
+	}
+	return fmt.Sprintf("%s%s", strings.TrimRight(base, "/"), uri)
+}
+
+// Posts the form to the given OAuth endpoint, and unmarshals the JSON
+// response into v. GitHub reports most OAuth errors with an HTTP 200, so
+// those are picked out of the response body.
+func (a *OAuthApp) post(ctx context.Context, uri string, form url.Values, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, "POST", a.webUrl(uri), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	client := a.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	var oauthErr OAuthError
+	if err := json.Unmarshal(body, &oauthErr); err == nil && oauthErr.Code != "" {
+		return &oauthErr
+	}
+
+	if response.StatusCode != http.StatusOK {
+		e := "GitHub responded with HTTP %d"
+		return errors.New(fmt.Sprintf(e, response.StatusCode))
+	}
+
+	return json.Unmarshal(body, v)
+}
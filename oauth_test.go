@@ -0,0 +1,202 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Starts a fake GitHub that serves the OAuth endpoints, as well as the API
+// (under "/api/v3"). The server is closed once the test is over.
+func newOAuthServer(t *testing.T, devicePolls []string) *httptest.Server {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.URL.Path != "/api/v3/user" {
+			if err := r.ParseForm(); err != nil {
+				t.Error(err)
+				return
+			}
+			if r.PostForm.Get("client_id") != "Iv1.client" {
+				t.Errorf("Unexpected client_id %q", r.PostForm.Get("client_id"))
+			}
+		}
+
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			if r.PostForm.Get("grant_type") != "" {
+				mu.Lock()
+				defer mu.Unlock()
+				if len(devicePolls) > 0 {
+					fmt.Fprintf(w, `{"error": %q}`, devicePolls[0])
+					devicePolls = devicePolls[1:]
+					return
+				}
+			} else if r.PostForm.Get("code") != "good-code" || r.PostForm.Get("client_secret") != "shh" {
+				fmt.Fprint(w, `{"error": "bad_verification_code", "error_description": "The code passed is incorrect or expired."}`)
+				return
+			}
+			fmt.Fprint(w, `{"access_token": "gho_token", "token_type": "bearer", "scope": "repo,gist"}`)
+
+		case "/login/device/code":
+			fmt.Fprint(w, `{"device_code": "dc", "user_code": "WDJB-MJHT", "verification_uri": "https://github.com/login/device", "expires_in": 900, "interval": 1}`)
+
+		case "/api/v3/user":
+			if r.Header.Get("Authorization") != "Bearer gho_token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"login": "monalisa"}`)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOAuthAuthorizeUrl(t *testing.T) {
+	app := &OAuthApp{
+		ClientId:    "Iv1.client",
+		RedirectUrl: "https://dashboards.example.com/callback",
+		Scopes:      []string{"read:org", "repo"},
+	}
+
+	u, err := url.Parse(app.AuthorizeUrl("xyzzy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "github.com" || u.Path != "/login/oauth/authorize" {
+		t.Errorf("Unexpected authorize URL %s", u)
+	}
+
+	query := u.Query()
+	expected := map[string]string{
+		"client_id":    "Iv1.client",
+		"redirect_uri": "https://dashboards.example.com/callback",
+		"scope":        "read:org repo",
+		"state":        "xyzzy",
+	}
+	for k, v := range expected {
+		if query.Get(k) != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, query.Get(k))
+		}
+	}
+}
+
+func TestNewOAuthState(t *testing.T) {
+	a, err := NewOAuthState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewOAuthState()
+	if len(a) < 32 || a == b {
+		t.Errorf("States %q and %q are not random enough", a, b)
+	}
+}
+
+func TestOAuthWebFlow(t *testing.T) {
+	server := newOAuthServer(t, nil)
+
+	app := &OAuthApp{ClientId: "Iv1.client", ClientSecret: "shh", WebUrl: server.URL}
+	ctx := context.Background()
+
+	callback := httptest.NewRequest("GET", "/callback?code=good-code&state=forged", nil)
+	if _, err := app.HandleCallback(ctx, callback, "xyzzy"); err != ErrOAuthStateMismatch {
+		t.Errorf("Expected a state mismatch, got %v", err)
+	}
+
+	callback = httptest.NewRequest("GET", "/callback?code=bad-code&state=xyzzy", nil)
+	var oauthErr *OAuthError
+	if _, err := app.HandleCallback(ctx, callback, "xyzzy"); !errors.As(err, &oauthErr) || oauthErr.Code != "bad_verification_code" {
+		t.Errorf("Expected a bad_verification_code error, got %v", err)
+	}
+
+	callback = httptest.NewRequest("GET", "/callback?error=access_denied&state=xyzzy", nil)
+	if _, err := app.HandleCallback(ctx, callback, "xyzzy"); !errors.As(err, &oauthErr) || oauthErr.Code != "access_denied" {
+		t.Errorf("Expected an access_denied error, got %v", err)
+	}
+
+	callback = httptest.NewRequest("GET", "/callback?code=good-code&state=xyzzy", nil)
+	token, err := app.HandleCallback(ctx, callback, "xyzzy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "gho_token" {
+		t.Errorf("Unexpected access token %q", token.AccessToken)
+	}
+
+	// Room to spare in the caller's options must not be written to.
+	opts := make([]Option, 1, 4)
+	opts[0] = WithBaseUrl(server.URL+"/api/v3", "")
+	g, err := app.Session(token, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extra := opts[1:cap(opts)]; extra[0] != nil || extra[1] != nil || extra[2] != nil {
+		t.Error("Expected the caller's options to be left alone")
+	}
+	if !g.HasScope("public_repo") || g.HasScope("user") {
+		t.Errorf("Unexpected session scopes %q", g.Scopes)
+	}
+
+	user, _, err := g.GetCurrentUser()
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Login != "monalisa" {
+		t.Errorf("Expected monalisa, got %q", user.Login)
+	}
+}
+
+func TestOAuthDeviceFlow(t *testing.T) {
+	server := newOAuthServer(t, []string{"authorization_pending", "slow_down", "authorization_pending"})
+
+	app := &OAuthApp{ClientId: "Iv1.client", WebUrl: server.URL, Scopes: []string{"repo"}, pollUnit: time.Millisecond}
+	ctx := context.Background()
+
+	code, err := app.RequestDeviceCode(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code.UserCode != "WDJB-MJHT" {
+		t.Errorf("Unexpected user code %q", code.UserCode)
+	}
+
+	token, err := app.PollDeviceToken(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "gho_token" {
+		t.Errorf("Unexpected access token %q", token.AccessToken)
+	}
+}
+
+func TestOAuthDeviceFlowDenied(t *testing.T) {
+	server := newOAuthServer(t, []string{"authorization_pending", "access_denied"})
+
+	app := &OAuthApp{ClientId: "Iv1.client", WebUrl: server.URL, pollUnit: time.Millisecond}
+	var oauthErr *OAuthError
+	_, err := app.PollDeviceToken(context.Background(), &DeviceCode{DeviceCode: "dc", Interval: 1})
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "access_denied" {
+		t.Errorf("Expected an access_denied error, got %v", err)
+	}
+
+	server = newOAuthServer(t, []string{"expired_token"})
+	app.WebUrl = server.URL
+	if _, err := app.PollDeviceToken(context.Background(), &DeviceCode{DeviceCode: "dc", Interval: 1}); err != ErrDeviceCodeExpired {
+		t.Errorf("Expected the device code to expire, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := app.PollDeviceToken(ctx, &DeviceCode{DeviceCode: "dc"}); err != context.Canceled {
+		t.Errorf("Expected polling to be cancelled, got %v", err)
+	}
+}
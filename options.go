@@ -0,0 +1,220 @@
+package gothub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// An Option configures a session created by New.
+type Option func(*GitHub) error
+
+// Use the given HTTP client for all requests, instead of a fresh http.Client.
+// This is where timeouts, proxies, custom TLS roots and the like are set up.
+func WithHttpClient(client *http.Client) Option {
+	return func(g *GitHub) error {
+		if client == nil {
+			return errors.New("gothub: nil HTTP client")
+		}
+		g.httpClient = client
+		return nil
+	}
+}
+
+// Send all requests through the given transport.
+//
+// The transport is set on a copy of the session's HTTP client, so a client
+// passed in with WithHttpClient is left untouched; WithTransport must come
+// after WithHttpClient for both to take effect.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(g *GitHub) error {
+		if transport == nil {
+			return errors.New("gothub: nil transport")
+		}
+		client := *g.httpClient
+		client.Transport = transport
+		g.httpClient = &client
+		return nil
+	}
+}
+
+// Send the given value as the User-Agent header, instead of DefaultUserAgent.
+func WithUserAgent(userAgent string) Option {
+	return func(g *GitHub) error {
+		g.UserAgent = userAgent
+		return nil
+	}
+}
+
+// Talk to the API (and uploads API) rooted at the given URLs; see
+// EnterpriseGuest for details.
+func WithBaseUrl(baseUrl, uploadUrl string) Option {
+	return func(g *GitHub) error {
+		if baseUrl == "" {
+			return errors.New("gothub: empty base URL")
+		}
+		for _, u := range []string{baseUrl, uploadUrl} {
+			if u == "" {
+				continue
+			}
+			if parsed, err := url.Parse(u); err != nil {
+				return err
+			} else if !parsed.IsAbs() {
+				return errors.New(fmt.Sprintf("gothub: %q is not an absolute URL", u))
+			}
+		}
+		g.setUrls(baseUrl, uploadUrl)
+		return nil
+	}
+}
+
+// Authenticate using basic, username/password authentication.
+func WithBasicAuth(username, password string) Option {
+	return func(g *GitHub) error {
+		g.Authorization = fmt.Sprintf("Basic %s", hashAuth(username, password))
+		return nil
+	}
+}
+
+// Send the given one-time password with every request, for an account with
+// two-factor authentication enabled. It goes with WithBasicAuth; see
+// OTPRequiredError.
+func WithOTP(otp string) Option {
+	return func(g *GitHub) error {
+		if otp == "" {
+			return errors.New("gothub: empty one-time password")
+		}
+		g.otp = otp
+		return nil
+	}
+}
+
+// Authenticate using a personal access token (or an OAuth token), sent as
+// "Authorization: token <token>".
+func WithToken(token string) Option {
+	return func(g *GitHub) error {
+		if token == "" {
+			return errors.New("gothub: empty token")
+		}
+		g.Authorization = fmt.Sprintf("token %s", token)
+		return nil
+	}
+}
+
+// Spread requests across several personal access tokens (or OAuth tokens),
+// each with a rate limit of its own. Every request is sent with the token that
+// has the most calls left; a token that runs out is parked until its rate
+// limit window resets, and the request goes out again with another one. Only
+// once all of them have run out does the session fail with
+// ErrRateLimitReached (or, with WithRateLimitWait, wait for the first one to
+// reset).
+//
+// The tokens take the place of any other credentials the session was given.
+// See PoolStats for how much of their combined quota is left.
+func WithTokenPool(tokens ...string) Option {
+	return func(g *GitHub) error {
+		if len(tokens) == 0 {
+			return errors.New("gothub: no tokens for the pool")
+		}
+		authorizations := make([]string, len(tokens))
+		for i, token := range tokens {
+			if token == "" {
+				return errors.New("gothub: empty token")
+			}
+			authorizations[i] = fmt.Sprintf("token %s", token)
+		}
+		g.pool = newTokenPool(authorizations)
+		return nil
+	}
+}
+
+// Authenticate using an OAuth access token, sent as
+// "Authorization: Bearer <token>".
+func WithBearerToken(token string) Option {
+	return func(g *GitHub) error {
+		if token == "" {
+			return errors.New("gothub: empty token")
+		}
+		g.Authorization = fmt.Sprintf("Bearer %s", token)
+		return nil
+	}
+}
+
+// Hold back the session's requests as the throttle says: at most so many at
+// once, so many per second, and mutations one at a time. Requests wait their
+// turn until their context is done. See ThrottleStats for how much they are
+// held back.
+func WithThrottle(throttle Throttle) Option {
+	return func(g *GitHub) error {
+		if throttle.MaxConcurrent < 0 || throttle.RequestsPerSecond < 0 || throttle.Burst < 0 {
+			return errors.New(fmt.Sprintf("gothub: invalid throttle %+v", throttle))
+		}
+		g.throttle = newThrottle(throttle)
+		return nil
+	}
+}
+
+// Have the session wait for the rate limit to reset whenever it runs out of
+// calls, rather than failing with ErrRateLimitReached. The wait can still be
+// cut short by the context of the request.
+func WithRateLimitWait() Option {
+	return func(g *GitHub) error {
+		g.waitForRateLimit = true
+		return nil
+	}
+}
+
+// Try failed requests again according to the given policy; see RetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(g *GitHub) error {
+		if policy.MaxRetries < 0 {
+			return errors.New("gothub: negative number of retries")
+		}
+		g.retryPolicy = &policy
+		return nil
+	}
+}
+
+// Keep responses in the given cache, and revalidate them with conditional
+// requests; see CacheTransport.
+//
+// The cache wraps the transport of the session's HTTP client, so WithCache
+// must come after WithHttpClient and WithTransport.
+func WithCache(cache Cache) Option {
+	return func(g *GitHub) error {
+		if cache == nil {
+			return errors.New("gothub: nil cache")
+		}
+		client := *g.httpClient
+		client.Transport = &CacheTransport{Cache: cache, Transport: client.Transport}
+		g.httpClient = &client
+		return nil
+	}
+}
+
+// Tell the tracer about every request the session sends, and what came of it;
+// see Tracer. With bodies set, the events carry the request and response
+// bodies too (up to the first 64 KiB of each, with their tokens redacted).
+func WithTracer(tracer Tracer, bodies bool) Option {
+	return func(g *GitHub) error {
+		if tracer == nil {
+			return errors.New("gothub: nil tracer")
+		}
+		g.tracer = tracer
+		g.traceBodies = bodies
+		return nil
+	}
+}
+
+// Tell the metrics about every request the session sends, and what came of
+// it; see Metrics, and MemoryMetrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(g *GitHub) error {
+		if metrics == nil {
+			return errors.New("gothub: nil metrics")
+		}
+		g.metrics = metrics
+		return nil
+	}
+}
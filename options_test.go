@@ -0,0 +1,117 @@
+package gothub
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A RoundTripper that answers every request itself, without any network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// Builds a JSON response to the given request.
+func jsonResponse(r *http.Request, status int, body string) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}
+}
+
+func TestNewMakesNoRequests(t *testing.T) {
+	calls := 0
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(r, http.StatusOK, `{}`), nil
+	})
+
+	if _, err := New(WithTransport(transport)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("New made %d requests", calls)
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.String() != "https://ghe.example.com/api/v3/users/octocat" {
+			t.Errorf("Unexpected URL %s", r.URL)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "dashboards/1.0" {
+			t.Errorf("Unexpected User-Agent %q", ua)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Basic "+hashAuth("monalisa", "hunter2") {
+			t.Errorf("Unexpected Authorization %q", auth)
+		}
+		return jsonResponse(r, http.StatusOK, `{"login": "octocat"}`), nil
+	})
+
+	client := &http.Client{Timeout: time.Minute}
+	g, err := New(
+		WithHttpClient(client),
+		WithTransport(transport),
+		WithUserAgent("dashboards/1.0"),
+		WithBaseUrl("https://ghe.example.com/api/v3", ""),
+		WithBasicAuth("monalisa", "hunter2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Transport != nil {
+		t.Error("WithTransport modified the caller's HTTP client")
+	}
+	if g.httpClient.Timeout != time.Minute {
+		t.Error("WithTransport dropped the settings of the caller's HTTP client")
+	}
+
+	user, _, err := g.GetUser("octocat")
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Login != "octocat" {
+		t.Errorf("Expected octocat, got %q", user.Login)
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	g, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.BaseUrl != GitHubUrl || g.UploadUrl != GitHubUploadUrl {
+		t.Errorf("Unexpected URLs %q, %q", g.BaseUrl, g.UploadUrl)
+	}
+	if g.UserAgent != DefaultUserAgent {
+		t.Errorf("Unexpected User-Agent %q", g.UserAgent)
+	}
+	if g.Authorization != "" {
+		t.Errorf("A guest session should not have an Authorization")
+	}
+}
+
+func TestNewBadOptions(t *testing.T) {
+	bad := []Option{
+		WithHttpClient(nil),
+		WithTransport(nil),
+		WithBaseUrl("", ""),
+		WithBaseUrl("/api/v3", ""),
+		WithCache(nil),
+	}
+	for i, opt := range bad {
+		if _, err := New(opt); err == nil {
+			t.Errorf("Option #%d should have been rejected", i)
+		} else {
+			t.Logf("Option #%d: %s", i, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gothub
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -44,9 +45,17 @@ type Organization struct {
 }
 
 // Returns a complete Organization struct.
-func (g *GitHub) GetOrganization(name string) (org *Organization, err error) {
+func (g *GitHub) GetOrganization(name string) (org *Organization, resp *Response, err error) {
+	return g.GetOrganizationContext(context.Background(), name)
+}
+
+// Like GetOrganization, but the request is bound to ctx.
+func (g *GitHub) GetOrganizationContext(ctx context.Context, name string) (org *Organization, resp *Response, err error) {
 	uri := fmt.Sprintf("/orgs/%s", name)
-	err = g.callGithubApi("GET", uri, &org)
+	resp, err = g.callGithubApi(ctx, "GET", uri, &org)
+	if err != nil {
+		return nil, resp, err
+	}
 	org.g = g
 	return
 }
@@ -55,17 +64,36 @@ func (g *GitHub) GetOrganization(name string) (org *Organization, err error) {
 //
 // Please be aware that this method does not return a complete Organization struct;
 // for that, please refer to the (*GitHub).GetOrganization() method.
-func (g *GitHub) Organizations() (orgs []Organization, err error) {
-	err = g.callGithubApi("GET", "/user/orgs", &orgs)
-	return
+func (g *GitHub) Organizations() (orgs []Organization, resp *Response, err error) {
+	return g.OrganizationsContext(context.Background())
+}
+
+// Like Organizations, but the request is bound to ctx.
+func (g *GitHub) OrganizationsContext(ctx context.Context) (orgs []Organization, resp *Response, err error) {
+	return listAll(ctx, g.ListOrganizations(nil))
+}
+
+// Returns an iterator over the organizations the currently-authenticated user
+// is a member of, starting at the page selected by opts (which may be nil).
+func (g *GitHub) ListOrganizations(opts *ListOptions) *ListIterator[Organization] {
+	return newListIterator[Organization](g, "/user/orgs", opts)
 }
 
 // List all of the organizations the user is a member of.
 //
 // Please be aware that this method does not return a complete Organization struct;
 // for that, please refer to the (*GitHub).GetOrganization() method.
-func (u *User) Organizations() (orgs []Organization, err error) {
-	uri := fmt.Sprintf("/users/%s/orgs", u.Login)
-	err = u.g.callGithubApi("GET", uri, &orgs)
-	return
+func (u *User) Organizations() (orgs []Organization, resp *Response, err error) {
+	return u.OrganizationsContext(context.Background())
+}
+
+// Like Organizations, but the request is bound to ctx.
+func (u *User) OrganizationsContext(ctx context.Context) (orgs []Organization, resp *Response, err error) {
+	return listAll(ctx, u.ListOrganizations(nil))
+}
+
+// Returns an iterator over the organizations the user is a member of, starting
+// at the page selected by opts (which may be nil).
+func (u *User) ListOrganizations(opts *ListOptions) *ListIterator[Organization] {
+	return newListIterator[Organization](u.g, fmt.Sprintf("/users/%s/orgs", u.Login), opts)
 }
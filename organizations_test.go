@@ -3,11 +3,12 @@ package gothub
 import "testing"
 
 func TestGithubOrganizations(t *testing.T) {
-	orgs, err := tgh.Organizations()
+	tgh := replaySession(t)
+	orgs, _, err := tgh.Organizations()
 	if err != nil {
 		t.Errorf("%s", err)
 	} else {
-		t.Logf("You are a member of the following %s organizations:", len(orgs))
+		t.Logf("You are a member of the following %d organizations:", len(orgs))
 		for _, org := range orgs {
 			t.Logf("%d\t%s", org.Id, org.Login)
 		}
@@ -15,13 +16,14 @@ func TestGithubOrganizations(t *testing.T) {
 }
 
 func TestUserOrganizations(t *testing.T) {
-	user, err := tgh.GetUser("octocat")
+	tgh := replaySession(t)
+	user, _, err := tgh.GetUser("octocat")
 	if err != nil {
 		t.Errorf("%s", err)
 		return
 	}
 
-	orgs, err := user.Organizations()
+	orgs, _, err := user.Organizations()
 	if err != nil {
 		t.Errorf("%s", err)
 	} else {
@@ -33,7 +35,8 @@ func TestUserOrganizations(t *testing.T) {
 }
 
 func TestGetOrganization(t *testing.T) {
-	if org, err := tgh.GetOrganization("github"); err != nil {
+	tgh := replaySession(t)
+	if org, _, err := tgh.GetOrganization("github"); err != nil {
 		t.Errorf("%s", err)
 	} else {
 		t.Logf("Successfully fetched organization \"%s\"", org.Name)
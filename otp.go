@@ -0,0 +1,77 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The header the API asks for a one-time password with, and which carries it
+// in requests.
+const otpHeader = "X-GitHub-OTP"
+
+// Returned when the API turns away a request made with basic authentication,
+// because the account has two-factor authentication enabled and the request
+// did not come with a valid one-time password.
+//
+// Log in again with BasicLoginOTP (or New and WithOTP) once the user has the
+// code, or send it with a single request using WithRequestOTP:
+//
+//	g, err := gothub.BasicLogin(username, password)
+//	var otpErr *gothub.OTPRequiredError
+//	if errors.As(err, &otpErr) {
+//	    code := askUser(otpErr.Method)
+//	    g, err = gothub.BasicLoginOTP(username, password, code)
+//	}
+type OTPRequiredError struct {
+	// Where the user gets their one-time password from: "app" for an
+	// authenticator app, or "sms" for a text message.
+	Method string
+
+	*ErrorResponse
+}
+
+func (e *OTPRequiredError) Error() string {
+	return fmt.Sprintf("%s (a one-time password is required, via %s)", e.ErrorResponse.Error(), e.Method)
+}
+
+func (e *OTPRequiredError) Unwrap() error {
+	return e.ErrorResponse
+}
+
+// Reports whether err is the API asking for a one-time password.
+func IsOTPRequired(err error) bool {
+	var e *OTPRequiredError
+	return errors.As(err, &e)
+}
+
+// Turns the error for a response that asks for a one-time password, with an
+// "X-GitHub-OTP: required; <method>" header, into an *OTPRequiredError.
+func otpError(r *http.Response, e *ErrorResponse) error {
+	value := r.Header.Get(otpHeader)
+	if r.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(value, "required") {
+		return e
+	}
+	_, method, _ := strings.Cut(value, ";")
+	return &OTPRequiredError{Method: strings.TrimSpace(method), ErrorResponse: e}
+}
+
+type otpKey struct{}
+
+// Returns a copy of ctx that makes the requests it is passed to send the
+// given one-time password, overriding the one the session was given with
+// WithOTP, if any. Codes only last a short while, so this suits sessions that
+// need one for just the odd request.
+func WithRequestOTP(ctx context.Context, code string) context.Context {
+	return context.WithValue(ctx, otpKey{}, code)
+}
+
+// Returns the one-time password to send with requests bound to ctx.
+func (g *GitHub) otpFor(ctx context.Context) string {
+	if code, ok := ctx.Value(otpKey{}).(string); ok && code != "" {
+		return code
+	}
+	return g.otp
+}
@@ -0,0 +1,85 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// Answers requests like a GitHub that wants the given one-time password with
+// every request, and counts the requests that came with it.
+func otpHandler(code string) (http.HandlerFunc, *int) {
+	accepted := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Header.Get("X-GitHub-OTP") != code {
+			w.Header().Set("X-GitHub-OTP", "required; app")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Must specify two-factor authentication OTP code."}`)
+			return
+		}
+		accepted++
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}, &accepted
+}
+
+func TestOTPRequired(t *testing.T) {
+	handler, _ := otpHandler("123456")
+	_, server := newTestSession(t, handler)
+
+	_, err := login(WithBaseUrl(server.URL, ""), WithBasicAuth("octocat", "hunter2"))
+	var otpErr *OTPRequiredError
+	if !errors.As(err, &otpErr) {
+		t.Fatalf("Expected an *OTPRequiredError, got %v", err)
+	}
+	if otpErr.Method != "app" || !IsOTPRequired(err) {
+		t.Errorf("Unexpected OTP method %q", otpErr.Method)
+	}
+	if !hasStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Expected the error to still be an HTTP 401, got %v", err)
+	}
+
+	// A plain 401 is not about OTPs.
+	response := &http.Response{StatusCode: http.StatusUnauthorized, Header: make(http.Header)}
+	if err := otpError(response, &ErrorResponse{Response: response}); IsOTPRequired(err) {
+		t.Errorf("Did not expect an *OTPRequiredError, got %v", err)
+	}
+}
+
+func TestWithOTP(t *testing.T) {
+	handler, accepted := otpHandler("123456")
+	_, server := newTestSession(t, handler)
+
+	g, err := login(WithBaseUrl(server.URL, ""), WithBasicAuth("octocat", "hunter2"), WithOTP("123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if *accepted != 2 {
+		t.Errorf("Expected the login and the call to send the OTP, got %d", *accepted)
+	}
+
+	if _, err := New(WithOTP("")); err == nil {
+		t.Error("Expected an empty one-time password to be rejected")
+	}
+}
+
+func TestWithRequestOTP(t *testing.T) {
+	handler, accepted := otpHandler("654321")
+	g, _ := newTestSession(t, handler, WithBasicAuth("octocat", "hunter2"), WithOTP("123456"))
+
+	ctx := WithRequestOTP(context.Background(), "654321")
+	if _, _, err := g.GetUserContext(ctx, "octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.GetUser("octocat"); !IsOTPRequired(err) {
+		t.Errorf("Expected the session's own OTP to be sent, got %v", err)
+	}
+	if *accepted != 1 {
+		t.Errorf("Expected one request with the OTP, got %d", *accepted)
+	}
+}
@@ -0,0 +1,144 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Selects a page of a list. The zero value gets the first page, with the API's
+// default page size (DefaultPageSize).
+type ListOptions struct {
+	Page    int
+	PerPage int
+}
+
+// Adds the list options to the query string of the uri.
+func (o *ListOptions) addTo(uri string) string {
+	if o == nil || (o.Page == 0 && o.PerPage == 0) {
+		return uri
+	}
+
+	v := url.Values{}
+	if o.Page > 0 {
+		v.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PerPage > 0 {
+		v.Set("per_page", strconv.Itoa(o.PerPage))
+	}
+	return addQuery(uri, v)
+}
+
+// Walks through a list, a page at a time, by following the rel="next" links
+// of the API's responses:
+//
+//	it := user.ListFollowers(&gothub.ListOptions{PerPage: 100})
+//	for it.Next(ctx) {
+//	    follower := it.Value()
+//	    ...
+//	}
+//	if err := it.Err(); err != nil {
+//	    ...
+//	}
+type ListIterator[T any] struct {
+	g       *GitHub
+	nextUrl string
+
+	page     []T
+	index    int
+	response *Response
+	err      error
+}
+
+// Creates an iterator over the list at uri, starting at the page selected by
+// opts.
+func newListIterator[T any](g *GitHub, uri string, opts *ListOptions) *ListIterator[T] {
+	return &ListIterator[T]{g: g, nextUrl: opts.addTo(uri), index: -1}
+}
+
+// Advances to the next item of the list, fetching the next page when the
+// current one runs out. It returns false once the list is exhausted, when a
+// page cannot be fetched, or when ctx is done; check Err to tell them apart.
+func (it *ListIterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.index++
+	for it.index >= len(it.page) {
+		if it.nextUrl == "" || !it.fetch(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetches the next page of the list.
+func (it *ListIterator[T]) fetch(ctx context.Context) bool {
+	if it.err = ctx.Err(); it.err != nil {
+		return false
+	}
+
+	// The links come from the API's responses; the session's credentials
+	// must not follow one to another host.
+	if u, err := url.Parse(it.nextUrl); err != nil || (u.IsAbs() && !it.g.apiHost(u)) {
+		it.err = errors.New(fmt.Sprintf("%q is not a link to the API", it.nextUrl))
+		return false
+	}
+
+	page := make([]T, 0)
+	it.response, it.err = it.g.callGithubApi(ctx, "GET", it.nextUrl, &page)
+	if it.err != nil {
+		return false
+	}
+	it.page, it.index = page, 0
+	it.nextUrl = it.response.nextUrl
+	return true
+}
+
+// Returns the current item of the list.
+func (it *ListIterator[T]) Value() T {
+	return it.page[it.index]
+}
+
+// Returns the error that stopped the iteration, if any.
+func (it *ListIterator[T]) Err() error {
+	return it.err
+}
+
+// Returns the response the current page came in, which is nil until Next has
+// been called.
+func (it *ListIterator[T]) Response() *Response {
+	return it.response
+}
+
+// Collects the rest of the list, following the rel="next" links to the end.
+func (it *ListIterator[T]) All(ctx context.Context) ([]T, error) {
+	items := make([]T, 0)
+	for it.Next(ctx) {
+		items = append(items, it.Value())
+	}
+	return items, it.Err()
+}
+
+// Collects the whole list, returning the response the last page came in.
+func listAll[T any](ctx context.Context, it *ListIterator[T]) ([]T, *Response, error) {
+	items, err := it.All(ctx)
+	return items, it.Response(), err
+}
+
+// Backs the older list methods, which take an optional page number: given one,
+// only that page is fetched; otherwise, the whole list is.
+func listPageOrAll[T any](ctx context.Context, list func(*ListOptions) *ListIterator[T], pages []int) ([]T, *Response, error) {
+	if len(pages) == 0 {
+		return listAll(ctx, list(nil))
+	}
+
+	it := list(&ListOptions{Page: pages[0]})
+	if !it.fetch(ctx) {
+		return make([]T, 0), it.Response(), it.Err()
+	}
+	return it.page, it.Response(), nil
+}
@@ -0,0 +1,158 @@
+package gothub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Starts a fake GitHub that serves a list of n followers, perPage at a time,
+// with pagination links like the API's, and returns a session pointed at it.
+func newPaginatedServer(t *testing.T, n, perPage int) (*GitHub, *int) {
+	requests := 0
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/users/octocat/followers" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		last := (n + perPage - 1) / perPage
+
+		link := func(page int, rel string) string {
+			return fmt.Sprintf(`<http://%s/users/octocat/followers?page=%d>; rel="%s"`, r.Host, page, rel)
+		}
+		links := link(1, "first") + ", " + link(last, "last")
+		if page < last {
+			links = link(page+1, "next") + ", " + links
+		}
+		if page > 1 {
+			links = link(page-1, "prev") + ", " + links
+		}
+		w.Header().Set("Link", links)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		body := "["
+		for i := (page - 1) * perPage; i < page*perPage && i < n; i++ {
+			if i > (page-1)*perPage {
+				body += ","
+			}
+			body += fmt.Sprintf(`{"login": "follower%d", "id": %d}`, i, i)
+		}
+		fmt.Fprint(w, body+"]")
+	})
+	return g, &requests
+}
+
+func TestListIteratorFollowsNextLinks(t *testing.T) {
+	g, requests := newPaginatedServer(t, 7, 3)
+	u := User{Login: "octocat", g: g}
+
+	it := u.ListFollowers(nil)
+	if it.Response() != nil {
+		t.Error("Expected no response before the first page")
+	}
+
+	ids := []int{}
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().Id)
+		if r := it.Response(); r.LastPage != 3 || r.FirstPage != 1 {
+			t.Errorf("Unexpected pages %+v", r)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 7 || ids[0] != 0 || ids[6] != 6 {
+		t.Errorf("Unexpected followers %v", ids)
+	}
+	if *requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", *requests)
+	}
+	if r := it.Response(); r.NextPage != 0 || r.PrevPage != 2 {
+		t.Errorf("Unexpected pages on the last page %+v", r)
+	}
+}
+
+func TestListIteratorStartsAtPage(t *testing.T) {
+	g, _ := newPaginatedServer(t, 7, 3)
+	u := User{Login: "octocat", g: g}
+
+	followers, err := u.ListFollowers(&ListOptions{Page: 2, PerPage: 3}).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(followers) != 4 || followers[0].Login != "follower3" {
+		t.Errorf("Unexpected followers %+v", followers)
+	}
+}
+
+func TestGetFollowersFetchesAllPages(t *testing.T) {
+	g, _ := newPaginatedServer(t, 5, 2)
+
+	followers, _, err := (&User{Login: "octocat", g: g}).GetFollowers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(followers) != 5 {
+		t.Errorf("Expected 5 followers, got %d", len(followers))
+	}
+}
+
+func TestListIteratorStopsOnCancel(t *testing.T) {
+	g, requests := newPaginatedServer(t, 6, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := (&User{Login: "octocat", g: g}).ListFollowers(nil)
+	for it.Next(ctx) {
+		cancel()
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", it.Err())
+	}
+	if *requests != 1 {
+		t.Errorf("Expected 1 request, got %d", *requests)
+	}
+}
+
+func TestListOptionsQuery(t *testing.T) {
+	var opts *ListOptions
+	if uri := opts.addTo("/user/repos"); uri != "/user/repos" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	opts = &ListOptions{Page: 2, PerPage: 50}
+	if uri := opts.addTo("/user/repos"); uri != "/user/repos?page=2&per_page=50" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	if uri := opts.addTo("/search/users?q=x"); uri != "/search/users?q=x&page=2&per_page=50" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+}
+
+func TestListIteratorStaysOnTheApiHost(t *testing.T) {
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Followed a link to another host, with Authorization %q", r.Header.Get("Authorization"))
+	}))
+	defer elsewhere.Close()
+
+	g, _ := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/users/octocat/followers?page=2>; rel="next"`, elsewhere.URL))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `[{"login": "hubot"}]`)
+	}, WithToken("secret"))
+	user := &User{Login: "octocat", g: g}
+	followers, err := user.ListFollowers(nil).All(context.Background())
+	if err == nil {
+		t.Error("Expected the link to another host to be refused")
+	}
+	if len(followers) != 1 || followers[0].Login != "hubot" {
+		t.Errorf("Expected the first page, got %+v", followers)
+	}
+}
@@ -0,0 +1,115 @@
+package gothub
+
+import (
+	"math"
+	"sync"
+)
+
+// The credentials a pooled session spreads its requests across; see
+// WithTokenPool. Each token is kept in a session of its own, which tracks the
+// token's rate limit just like any other session does.
+type tokenPool struct {
+	mu     sync.Mutex
+	tokens []*GitHub
+}
+
+func newTokenPool(authorizations []string) *tokenPool {
+	p := &tokenPool{}
+	for _, authorization := range authorizations {
+		p.tokens = append(p.tokens, &GitHub{Authorization: authorization})
+	}
+	return p
+}
+
+// Picks the token to send the next request with: the one with the most calls
+// remaining, of those that are not parked. Tokens that have not been used yet
+// are assumed to have all of their calls left. Should every token be parked,
+// the one whose window resets first is picked, for the caller to wait on (or
+// fail with ErrRateLimitReached).
+func (p *tokenPool) pick() *GitHub {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best, soonest *GitHub
+	bestRemaining := -1
+	for _, t := range p.tokens {
+		rate := t.Rate()
+		if t.rateLimited() {
+			if soonest == nil || rate.Reset.Before(soonest.Rate().Reset) {
+				soonest = t
+			}
+			continue
+		}
+		remaining := rate.Remaining
+		if rate.Limit == 0 {
+			remaining = math.MaxInt
+		}
+		if remaining > bestRemaining {
+			best, bestRemaining = t, remaining
+		}
+	}
+	if best == nil {
+		return soonest
+	}
+	return best
+}
+
+// Reports whether any of the tokens has calls left.
+func (p *tokenPool) available() bool {
+	for _, t := range p.tokens {
+		if !t.rateLimited() {
+			return true
+		}
+	}
+	return false
+}
+
+// The quota of a session that spreads its requests across several tokens
+// (see WithTokenPool), as of the last responses from the API.
+type PoolStats struct {
+	// How many tokens the session has, and how many of them are parked
+	// because they ran out of calls, until their window resets.
+	Tokens int
+	Parked int
+
+	// The combined rate limit of the tokens. Limit, Remaining and Used add up
+	// those of the tokens; Reset is the soonest time any of the tokens' window
+	// ends.
+	Rate Rate
+
+	// The rate limit of each token, in the order they were given.
+	TokenRates []Rate
+}
+
+func (p *tokenPool) stats() PoolStats {
+	stats := PoolStats{Tokens: len(p.tokens)}
+	for _, t := range p.tokens {
+		rate := t.Rate()
+		if t.rateLimited() {
+			stats.Parked++
+		}
+		stats.Rate.Limit += rate.Limit
+		stats.Rate.Remaining += rate.Remaining
+		stats.Rate.Used += rate.Used
+		if !rate.Reset.IsZero() && (stats.Rate.Reset.IsZero() || rate.Reset.Before(stats.Rate.Reset)) {
+			stats.Rate.Reset = rate.Reset
+		}
+		stats.TokenRates = append(stats.TokenRates, rate)
+	}
+	return stats
+}
+
+// Returns the quota of a session that spreads its requests across several
+// tokens. For any other session, it is the quota of its one set of
+// credentials.
+func (g *GitHub) PoolStats() PoolStats {
+	if g.pool == nil {
+		rate := g.Rate()
+		stats := PoolStats{Tokens: 1, Rate: rate, TokenRates: []Rate{rate}}
+		if g.rateLimited() {
+			stats.Parked = 1
+		}
+		return stats
+	}
+	return g.pool.stats()
+}
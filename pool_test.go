@@ -0,0 +1,110 @@
+package gothub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Answers requests like a GitHub that gives each of the tokens its own rate
+// limit, and counts the calls made with each of them.
+func tokenPoolHandler(limits map[string]int) (http.HandlerFunc, map[string]int) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	reset := time.Now().Add(time.Hour)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "token ")
+		limit, ok := limits[token]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if calls[token] == limit {
+			h.Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+			return
+		}
+		calls[token]++
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(limit-calls[token]))
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}, calls
+}
+
+func TestTokenPool(t *testing.T) {
+	handler, calls := tokenPoolHandler(map[string]int{"a": 5, "b": 3})
+	g, _ := newTestSession(t, handler, WithTokenPool("a", "b"))
+
+	for i := 0; i < 8; i++ {
+		if _, _, err := g.GetUser("octocat"); err != nil {
+			t.Fatalf("Call #%d: %s", i+1, err)
+		}
+	}
+	if calls["a"] != 5 || calls["b"] != 3 {
+		t.Errorf("Expected the calls to follow the tokens' quotas, got %v", calls)
+	}
+
+	stats := g.PoolStats()
+	if stats.Tokens != 2 || stats.Parked != 2 || len(stats.TokenRates) != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if rate := g.Rate(); rate != stats.Rate || rate.Limit != 8 || rate.Remaining != 0 || rate.Used != 8 {
+		t.Errorf("Unexpected combined rate %+v", rate)
+	}
+
+	if _, _, err := g.GetUser("octocat"); !errors.Is(err, ErrRateLimitReached) {
+		t.Errorf("Expected all of the tokens to be parked, got %v", err)
+	}
+}
+
+func TestTokenPoolParksExhaustedTokens(t *testing.T) {
+	handler, calls := tokenPoolHandler(map[string]int{"a": 1, "b": 2})
+	calls["a"] = 1
+	g, _ := newTestSession(t, handler, WithTokenPool("a", "b"))
+
+	// The pool does not know a is out of calls until the API says so; the
+	// request then goes out again with b.
+	for i := 0; i < 2; i++ {
+		if _, _, err := g.GetUser("octocat"); err != nil {
+			t.Fatalf("Call #%d: %s", i+1, err)
+		}
+	}
+	if calls["a"] != 1 || calls["b"] != 2 {
+		t.Errorf("Expected b to make both calls, got %v", calls)
+	}
+	stats := g.PoolStats()
+	if stats.Parked != 2 || stats.TokenRates[0].Remaining != 0 || stats.TokenRates[0].Reset.IsZero() {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestWithTokenPoolValidates(t *testing.T) {
+	if _, err := New(WithTokenPool()); err == nil {
+		t.Error("Expected a pool without tokens to be rejected")
+	}
+	if _, err := New(WithTokenPool("a", "")); err == nil {
+		t.Error("Expected an empty token to be rejected")
+	}
+
+	g, err := New(WithToken("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := g.PoolStats(); stats.Tokens != 1 || stats.Parked != 0 {
+		t.Errorf("Unexpected stats for a single token %+v", stats)
+	}
+}
@@ -0,0 +1,129 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errNoRewind = errors.New("gothub: request body cannot be replayed")
+
+// The rate limit of a session, as last reported by the API, and described
+// here: http://developer.github.com/v3/#rate-limiting
+type Rate struct {
+	Limit     int
+	Remaining int
+	Used      int
+
+	// When the current window ends, and Remaining goes back up to Limit.
+	Reset time.Time
+}
+
+// Returns the session's rate limit, as of the last response from the API. For
+// a session with a pool of tokens, it is their combined rate limit; see
+// PoolStats.
+func (g *GitHub) Rate() Rate {
+	if g.pool != nil {
+		return g.pool.stats().Rate
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return Rate{
+		Limit:     g.RateLimit,
+		Remaining: g.RateLimitRemaining,
+		Used:      g.RateLimitUsed,
+		Reset:     g.RateLimitReset,
+	}
+}
+
+// Reports whether the session has used up its calls for the current rate limit
+// window. Until the API has told us what the limit is, we assume it has not;
+// once the window has reset, the next request will tell us again.
+func (g *GitHub) rateLimited() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.RateLimit == 0 || g.RateLimitRemaining > 0 {
+		return false
+	}
+	return g.RateLimitReset.IsZero() || time.Now().Before(g.RateLimitReset)
+}
+
+// Sleeps until the rate limit resets, or until ctx is done.
+func (g *GitHub) waitForReset(ctx context.Context) error {
+	reset := g.Rate().Reset
+	if reset.IsZero() {
+		// There is no telling how long we would have to wait.
+		return ErrRateLimitReached
+	}
+
+	// The reset time only has a resolution of seconds, and our clock may not
+	// quite agree with GitHub's, so we hang on for an extra second.
+	return sleep(ctx, time.Until(reset)+time.Second)
+}
+
+// Updates the call limit rates in the GitHub struct.
+func (g *GitHub) updateRates(r *http.Response) {
+	rate, ok := parseRate(r.Header)
+	if !ok || r.Header.Get("X-RateLimit-Resource") == "graphql" {
+		// GraphQL has a rate limit of its own; see GraphQLRate.
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.RateLimit = rate.Limit
+	g.RateLimitRemaining = rate.Remaining
+	g.RateLimitUsed = rate.Used
+	if !rate.Reset.IsZero() {
+		g.RateLimitReset = rate.Reset
+	}
+}
+
+// Parses the rate limit headers of a response; ok is false when the response
+// does not have them.
+func parseRate(h http.Header) (rate Rate, ok bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	rate.Limit, rate.Remaining = limit, remaining
+
+	if used, err := strconv.Atoi(h.Get("X-RateLimit-Used")); err == nil {
+		rate.Used = used
+	} else {
+		rate.Used = limit - remaining
+	}
+
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rate.Reset = time.Unix(reset, 0)
+	}
+	return rate, true
+}
+
+// Returns a copy of the request that can be sent again, with its body back at
+// the start.
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errNoRewind
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	rewound := req.Clone(req.Context())
+	rewound.Body = body
+	return rewound, nil
+}
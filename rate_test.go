@@ -0,0 +1,120 @@
+package gothub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Answers requests like a GitHub with a rate limit of the given number of
+// calls, whose window resets a second after it runs out.
+func rateLimitedHandler(limit int) http.HandlerFunc {
+	var mu sync.Mutex
+	remaining := limit
+	var reset time.Time
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if remaining == 0 && !now.Before(reset) {
+			remaining = limit
+		}
+		if remaining == limit {
+			reset = now.Add(time.Second)
+		}
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		h.Set("Content-Type", "application/json; charset=utf-8")
+
+		if remaining == 0 {
+			h.Set("X-RateLimit-Remaining", "0")
+			h.Set("X-RateLimit-Used", strconv.Itoa(limit))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+			return
+		}
+
+		remaining--
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("X-RateLimit-Used", strconv.Itoa(limit-remaining))
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}
+}
+
+func TestRate(t *testing.T) {
+	g, _ := newTestSession(t, rateLimitedHandler(5))
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+
+	rate := g.Rate()
+	if rate.Limit != 5 || rate.Remaining != 4 || rate.Used != 1 {
+		t.Errorf("Unexpected rate %+v", rate)
+	}
+	if rate.Reset.Before(time.Now().Add(-time.Second)) || rate.Reset.After(time.Now().Add(2*time.Second)) {
+		t.Errorf("Unexpected reset time %s", rate.Reset)
+	}
+}
+
+func TestRateLimitResets(t *testing.T) {
+	g := &GitHub{RateLimit: 60, RateLimitRemaining: 0}
+	if !g.rateLimited() {
+		t.Error("A session without a known reset time should stay limited")
+	}
+
+	g.RateLimitReset = time.Now().Add(time.Minute)
+	if !g.rateLimited() {
+		t.Error("The session should be limited until the reset time")
+	}
+
+	g.RateLimitReset = time.Now().Add(-time.Second)
+	if g.rateLimited() {
+		t.Error("The session should no longer be limited after the reset time")
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	g, _ := newTestSession(t, rateLimitedHandler(1), WithRateLimitWait())
+
+	// The second call has to wait for the window to reset before it is made.
+	for i := 0; i < 2; i++ {
+		if _, _, err := g.GetUser("octocat"); err != nil {
+			t.Fatalf("Call #%d: %s", i+1, err)
+		}
+	}
+
+	// Should the session not know it is out of calls, it is turned away by
+	// the API, and tries again once the window has reset.
+	g.RateLimitRemaining = 1
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatalf("Call #3: %s", err)
+	}
+}
+
+func TestRateLimitWaitCancel(t *testing.T) {
+	g := &GitHub{
+		httpClient:       &http.Client{},
+		RateLimit:        60,
+		RateLimitReset:   time.Now().Add(time.Hour),
+		waitForRateLimit: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.GetUserContext(ctx, "octocat"); err != context.DeadlineExceeded {
+		t.Errorf("Expected the wait to be cut short, got %v", err)
+	}
+
+	g.waitForRateLimit = false
+	if _, _, err := g.GetUser("octocat"); err != ErrRateLimitReached {
+		t.Errorf("Expected ErrRateLimitReached, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package replay records the HTTP interactions of a test, and plays them back
+// on later runs, so that tests which talk to the GitHub API can run without
+// network access or credentials.
+//
+// The interactions are kept as JSON fixture files, which are meant to be
+// checked in. Credentials are scrubbed from them as they are recorded:
+// headers such as Authorization, and token fields in bodies, are redacted the
+// same way gothub's tracer redacts them, as is any of the Transport's Secrets
+// wherever it turns up.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nesv/gothub/internal/redact"
+)
+
+// Whether a Transport sends requests and records them, or plays back the
+// interactions it recorded before.
+type Mode int
+
+const (
+	ModeReplay Mode = iota
+	ModeRecord
+)
+
+// What scrubbed credentials are replaced with.
+const Redacted = redact.Placeholder
+
+// A request, and the response it got, as kept in a fixture file.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	Url    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// A transport that either records the interactions of the requests sent
+// through it, or plays back the ones recorded before. It is safe for
+// concurrent use.
+//
+// When replaying, each request gets the response of the first recorded
+// interaction with the same method, path, query and body that has not been
+// played back yet. A request without one fails.
+type Transport struct {
+	// Where requests are sent when recording; http.DefaultTransport when nil.
+	Transport http.RoundTripper
+
+	// Strings (such as passwords and tokens) to scrub from the URLs, headers
+	// and bodies of recorded interactions.
+	Secrets []string
+
+	path string
+	mode Mode
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	replayed     []bool
+}
+
+// Creates a transport that records into, or replays from, the fixture file at
+// path. When replaying, the file has to exist already.
+func New(path string, mode Mode) (*Transport, error) {
+	t := &Transport{path: path, mode: mode}
+	if mode == ModeRecord {
+		return t, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, errors.New(fmt.Sprintf("replay: %s: %s", path, err))
+	}
+	t.interactions = f.Interactions
+	t.replayed = make([]bool, len(f.Interactions))
+	return t, nil
+}
+
+// Returns the mode the transport was created with.
+func (t *Transport) Mode() Mode {
+	return t.mode
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.mode == ModeRecord {
+		return t.record(req, body)
+	}
+	return t.replay(req, body)
+}
+
+func (t *Transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	sent := req.Clone(req.Context())
+	sent.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	rt := t.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	response, err := rt.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	i := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			Url:    t.scrub(redact.Url(req.URL)),
+			Header: t.scrubHeader(req.Header),
+			Body:   t.scrub(string(redact.Body(body))),
+		},
+		Response: Response{
+			StatusCode: response.StatusCode,
+			Header:     t.scrubHeader(response.Header),
+			Body:       t.scrub(string(redact.Body(responseBody))),
+		},
+	}
+
+	t.mu.Lock()
+	t.interactions = append(t.interactions, i)
+	t.replayed = append(t.replayed, true)
+	t.mu.Unlock()
+	return response, nil
+}
+
+func (t *Transport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	uri := requestUri(t.scrub(redact.Url(req.URL)))
+	scrubbed := t.scrub(string(redact.Body(body)))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for n, i := range t.interactions {
+		if t.replayed[n] || i.Request.Method != req.Method ||
+			requestUri(i.Request.Url) != uri || i.Request.Body != scrubbed {
+			continue
+		}
+		t.replayed[n] = true
+
+		header := i.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+			StatusCode:    i.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("replay: %s: no recorded interaction left for %s %s", t.path, req.Method, uri))
+}
+
+// Writes the recorded interactions to the fixture file, creating its directory
+// if need be. It does nothing when replaying.
+func (t *Transport) Save() error {
+	if t.mode != ModeRecord {
+		return nil
+	}
+
+	t.mu.Lock()
+	data, err := json.MarshalIndent(fixture{Interactions: t.interactions}, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.path, append(data, '\n'), 0644)
+}
+
+// Replaces the secrets in s.
+func (t *Transport) scrub(s string) string {
+	for _, secret := range t.Secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+func (t *Transport) scrubHeader(h http.Header) http.Header {
+	scrubbed := make(http.Header, len(h))
+	for name, values := range h {
+		for _, v := range values {
+			scrubbed.Add(name, t.scrub(v))
+		}
+	}
+	return redact.Header(scrubbed)
+}
+
+// Returns the path and query of a URL, which is what interactions are matched
+// on, so that they can be played back against any host.
+func requestUri(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return u.RequestURI()
+}
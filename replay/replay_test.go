@@ -0,0 +1,80 @@
+package replay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=hunter2")
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"echo": ` + string(body) + `, "token": "hunter2"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "fixtures", "interactions.json")
+	recorder, err := New(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Secrets = []string{"hunter2"}
+
+	client := &http.Client{Transport: recorder}
+	req, _ := http.NewRequest("POST", server.URL+"/user/keys?access_token=hunter2", strings.NewReader(`"key"`))
+	req.Header.Set("Authorization", "token hunter2")
+	response, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, _ := ioutil.ReadAll(response.Body)
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("A secret made it into the fixture:\n%s", data)
+	}
+
+	player, err := New(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: player}
+
+	// The host does not matter, and neither do the credentials.
+	req, _ = http.NewRequest("POST", "https://api.github.com/user/keys?access_token=swordfish", strings.NewReader(`"key"`))
+	response, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusCreated || response.Header.Get("Set-Cookie") != Redacted {
+		t.Errorf("Unexpected response %d %v", response.StatusCode, response.Header)
+	}
+	if string(replayed) != strings.ReplaceAll(string(recorded), "hunter2", Redacted) {
+		t.Errorf("Expected %s, got %s", recorded, replayed)
+	}
+
+	// Every interaction is only played back once.
+	req, _ = http.NewRequest("POST", "https://api.github.com/user/keys?access_token=swordfish", strings.NewReader(`"key"`))
+	if _, err := client.Do(req); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("Expected no interaction to be left, got %v", err)
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Error("Expected an error for a missing fixture")
+	}
+}
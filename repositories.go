@@ -1,6 +1,7 @@
 package gothub
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -36,9 +37,9 @@ type Repository struct {
 	CloneUrl         string                `json:"clone_url"`
 	CollaboratorsUrl string                `json:"collaborators_url"`
 	CommentsUrl      string                `json:"comments_url"`
-	CommitsUrl       string                `json:"comments_url"`
+	CommitsUrl       string                `json:"commits_url"`
 	CompareUrl       string                `json:"compare_url"`
-	ContentsUrl      string                `json:"contentsUrl"`
+	ContentsUrl      string                `json:"contents_url"`
 	ContributorsUrl  string                `json:"contributors_url"`
 	CreatedAt        time.Time             `json:"created_at"`
 	DefaultBranch    string                `json:"default_branch"`
@@ -97,32 +98,36 @@ type Repository struct {
 	WatchersCount    int                   `json:"watchers_count"`
 }
 
-// Get the currently-authenticated user's repositories.
-func (g GitHub) Repositories(options ...int) (repositories []Repository, err error) {
-	var uri string
-	if len(options) > 0 {
-		page := options[0]
-		uri = fmt.Sprintf("/user/repos?page=%d", page)
-	} else {
-		uri = "/user/repos"
-	}
+// Get the currently-authenticated user's repositories. Given a page number,
+// only that page is fetched; otherwise, all of them are.
+func (g *GitHub) Repositories(options ...int) (repositories []Repository, resp *Response, err error) {
+	return g.RepositoriesContext(context.Background(), options...)
+}
+
+// Like Repositories, but the request is bound to ctx.
+func (g *GitHub) RepositoriesContext(ctx context.Context, options ...int) (repositories []Repository, resp *Response, err error) {
+	return listPageOrAll(ctx, g.ListRepositories, options)
+}
 
-	repositories = make([]Repository, 0)
-	err = g.callGithubApi("GET", uri, &repositories)
-	return
+// Returns an iterator over the currently-authenticated user's repositories,
+// starting at the page selected by opts (which may be nil).
+func (g *GitHub) ListRepositories(opts *ListOptions) *ListIterator[Repository] {
+	return newListIterator[Repository](g, "/user/repos", opts)
 }
 
-// Get the user's repositories.
-func (u User) Repositories(options ...int) (repositories []Repository, err error) {
-	var uri string
-	if len(options) > 0 {
-		page := options[0]
-		uri = fmt.Sprintf("/users/%s/repos?page=%d", u.Login, page)
-	} else {
-		uri = fmt.Sprintf("/users/%s/repos", u.Login)
-	}
+// Get the user's repositories. Given a page number, only that page is fetched;
+// otherwise, all of them are.
+func (u *User) Repositories(options ...int) (repositories []Repository, resp *Response, err error) {
+	return u.RepositoriesContext(context.Background(), options...)
+}
+
+// Like Repositories, but the request is bound to ctx.
+func (u *User) RepositoriesContext(ctx context.Context, options ...int) (repositories []Repository, resp *Response, err error) {
+	return listPageOrAll(ctx, u.ListRepositories, options)
+}
 
-	repositories = make([]Repository, 0)
-	err = u.g.callGithubApi("GET", uri, &repositories)
-	return
+// Returns an iterator over the user's repositories, starting at the page
+// selected by opts (which may be nil).
+func (u *User) ListRepositories(opts *ListOptions) *ListIterator[Repository] {
+	return newListIterator[Repository](u.g, fmt.Sprintf("/users/%s/repos", u.Login), opts)
 }
@@ -3,8 +3,9 @@ package gothub
 import "testing"
 
 func TestRepositories(t *testing.T) {
+	tgh := replaySession(t)
 	reposCount := 0
-	repos, err := tgh.Repositories()
+	repos, _, err := tgh.Repositories()
 	reposCount += len(repos)
 	if err != nil {
 		t.Errorf("%s", err)
@@ -15,7 +16,7 @@ func TestRepositories(t *testing.T) {
 		}
 	}
 
-	repos, err = tgh.Repositories(2)
+	repos, _, err = tgh.Repositories(2)
 	reposCount += len(repos)
 	if err != nil {
 		t.Errorf("%s", err)
@@ -28,13 +29,14 @@ func TestRepositories(t *testing.T) {
 }
 
 func TestUserRepositories(t *testing.T) {
-	user, err := tgh.GetUser("octocat")
+	tgh := replaySession(t)
+	user, _, err := tgh.GetUser("octocat")
 	if err != nil {
 		t.Errorf("%s", err)
 		return
 	}
 
-	repos, err := user.Repositories()
+	repos, _, err := user.Repositories()
 	if err != nil {
 		t.Errorf("%s", err)
 	} else {
@@ -45,7 +47,7 @@ func TestUserRepositories(t *testing.T) {
 		}
 	}
 
-	repos, err = user.Repositories(2)
+	repos, _, err = user.Repositories(2)
 	if err != nil {
 		t.Errorf("%s", err)
 	} else {
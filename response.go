@@ -0,0 +1,68 @@
+package gothub
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Wraps a response from the API, along with the metadata that has been parsed
+// out of its headers. Its body has already been read (and closed) by the time
+// it is handed back, except by DoStream.
+//
+// Every call returns it, alongside errors too, whenever the API answered at
+// all. Calls that collect a whole list return the response its last page came
+// in.
+type Response struct {
+	*http.Response
+
+	// The page numbers from the response's pagination links, or 0 for the
+	// links it does not have, as described here:
+	// http://developer.github.com/v3/#pagination
+	NextPage  int
+	PrevPage  int
+	FirstPage int
+	LastPage  int
+
+	// The rate limit, as of this response; the zero Rate if the response did
+	// not report it.
+	Rate Rate
+
+	// The ID GitHub gave the request (from the X-GitHub-Request-Id header),
+	// which GitHub Support asks for when looking into a problem.
+	RequestId string
+
+	// The URL of the next page, which not every list can express as a page
+	// number (some, for example, are paginated with a "since" parameter).
+	nextUrl string
+}
+
+// Returns nil for a nil response, so that callers can hand back whatever
+// (*GitHub).call came back with.
+func newResponse(r *http.Response) *Response {
+	if r == nil {
+		return nil
+	}
+
+	response := &Response{Response: r, RequestId: r.Header.Get("X-GitHub-Request-Id")}
+	response.Rate, _ = parseRate(r.Header)
+	for _, link := range parseLinkHeader(r) {
+		page := 0
+		if u, err := url.Parse(link.Url); err == nil {
+			page, _ = strconv.Atoi(u.Query().Get("page"))
+		}
+
+		switch link.Rel {
+		case "next":
+			response.NextPage = page
+			response.nextUrl = link.Url
+		case "prev":
+			response.PrevPage = page
+		case "first":
+			response.FirstPage = page
+		case "last":
+			response.LastPage = page
+		}
+	}
+	return response
+}
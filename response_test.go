@@ -0,0 +1,81 @@
+package gothub
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewResponse(t *testing.T) {
+	r := jsonResponse(nil, http.StatusOK, `[]`)
+	r.Header.Set("X-GitHub-Request-Id", "CAFE:1234:5678")
+	r.Header.Set("X-RateLimit-Limit", "5000")
+	r.Header.Set("X-RateLimit-Remaining", "4990")
+	r.Header.Set("X-RateLimit-Reset", "1700000000")
+	r.Header.Set("Link", `<https://api.github.com/user/repos?page=3&per_page=10>; rel="next", <https://api.github.com/user/repos?page=1&per_page=10>; rel="prev", <https://api.github.com/user/repos?page=9&per_page=10>; rel="last"`)
+
+	response := newResponse(r)
+	if response.RequestId != "CAFE:1234:5678" {
+		t.Errorf("Unexpected request ID %q", response.RequestId)
+	}
+	expected := Rate{Limit: 5000, Remaining: 4990, Used: 10, Reset: time.Unix(1700000000, 0)}
+	if response.Rate != expected {
+		t.Errorf("Expected rate %+v, got %+v", expected, response.Rate)
+	}
+	if response.NextPage != 3 || response.PrevPage != 1 || response.FirstPage != 0 || response.LastPage != 9 {
+		t.Errorf("Unexpected pages %+v", response)
+	}
+
+	if newResponse(nil) != nil {
+		t.Error("Expected no response for a nil *http.Response")
+	}
+}
+
+func TestResponseReturnedWithError(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		response := jsonResponse(r, http.StatusNotFound, `{"message": "Not Found"}`)
+		response.Header.Set("X-GitHub-Request-Id", "BEEF:1")
+		return response, nil
+	})
+	g, err := New(WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, resp, err := g.GetUserContext(context.Background(), "nobody")
+	if !IsNotFound(err) {
+		t.Errorf("Expected a 404, got %v", err)
+	}
+	if user != nil {
+		t.Errorf("Expected no user, got %+v", user)
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound || resp.RequestId != "BEEF:1" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
+
+func TestResponseReturnedFromMutations(t *testing.T) {
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		response := jsonResponse(r, http.StatusNoContent, ``)
+		response.Header.Set("X-GitHub-Request-Id", "F00D:2")
+		return response, nil
+	})
+	g, err := New(WithTransport(transport), WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.FollowContext(context.Background(), "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RequestId != "F00D:2" {
+		t.Errorf("Unexpected request ID %q", resp.RequestId)
+	}
+
+	// The calls without a context return it too.
+	if resp, err := g.Unfollow("octocat"); err != nil || resp.RequestId != "F00D:2" {
+		t.Errorf("Unexpected response %+v (%v)", resp, err)
+	}
+}
@@ -0,0 +1,216 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decides whether, and after how long, failed requests are tried again. A
+// request is retried when:
+//
+//   - it could not be sent at all, or the API answered with an HTTP 5xx or a
+//     Retry-After header, and its method is idempotent (or RetryAllMethods is
+//     set);
+//   - the API turned it away with an HTTP 429, or because it tripped a
+//     secondary rate limit, in which case the request was never acted upon.
+//
+// Waits asked for by Retry-After headers are capped at the longer of
+// MaxBackoff and SecondaryRateLimitWait.
+//
+// Running out of calls for the current rate limit window is not retried; see
+// WithRateLimitWait for that.
+//
+// The zero values of the durations are replaced by sensible defaults.
+type RetryPolicy struct {
+	// How many times a request is tried again, after the first attempt.
+	MaxRetries int
+
+	// The bounds of the exponential backoff between attempts, before jitter
+	// is applied. They default to one second, and thirty seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// How long to wait after tripping a secondary rate limit, when the API
+	// does not say; GitHub asks for at least a minute, which is the default.
+	SecondaryRateLimitWait time.Duration
+
+	// Also retry methods that are not idempotent (POST and PATCH) after
+	// transport errors and server errors, even though the API may have
+	// acted on them.
+	RetryAllMethods bool
+
+	// Called right before waiting to try a request again.
+	OnRetry func(RetryEvent)
+}
+
+// Describes a request that is about to be tried again.
+type RetryEvent struct {
+	Request *http.Request
+
+	// The response to, or the error from, the failed attempt; Response is nil
+	// when the request could not be sent.
+	Response *http.Response
+	Err      error
+
+	// Which retry this is, starting at 1, and how long until it is made.
+	Attempt int
+	Wait    time.Duration
+}
+
+// A policy of three retries with the default backoff.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3}
+
+// Returns how long to wait before trying the request again, and whether it
+// should be tried again at all.
+func (p *RetryPolicy) backoff(req *http.Request, response *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		// The request never made it to the API, or we could not read the
+		// response.
+		if !p.methodRetryable(req.Method) {
+			return 0, false
+		}
+		return p.exponential(attempt), true
+	}
+
+	// Requests turned away by a rate limit were never acted on, so they are
+	// safe to send again, whatever their method.
+	status := e.Response.StatusCode
+	wait, hasRetryAfter := retryAfter(e.Response)
+	if hasRetryAfter && (status == http.StatusTooManyRequests || isSecondaryRateLimit(e)) {
+		return p.capRetryAfter(wait), true
+	}
+	if isSecondaryRateLimit(e) {
+		wait := p.SecondaryRateLimitWait
+		if wait == 0 {
+			wait = time.Minute
+		}
+		if backoff := p.exponential(attempt); backoff > wait {
+			wait = backoff
+		}
+		return wait, true
+	}
+
+	if !p.methodRetryable(req.Method) {
+		return 0, false
+	}
+	if hasRetryAfter {
+		return p.capRetryAfter(wait), true
+	}
+	if status >= 500 && status != http.StatusNotImplemented {
+		return p.exponential(attempt), true
+	}
+	return 0, false
+}
+
+// Caps a wait asked for by a Retry-After header at the longer of MaxBackoff
+// and SecondaryRateLimitWait, so that a bogus header cannot hold a request
+// up for hours.
+func (p *RetryPolicy) capRetryAfter(wait time.Duration) time.Duration {
+	max, secondary := p.MaxBackoff, p.SecondaryRateLimitWait
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	if secondary <= 0 {
+		secondary = time.Minute
+	}
+	if secondary > max {
+		max = secondary
+	}
+	if wait > max {
+		return max
+	}
+	return wait
+}
+
+// Returns the exponential backoff for the given attempt, with "equal jitter"
+// applied: somewhere between half of, and the full, backoff.
+func (p *RetryPolicy) exponential(attempt int) time.Duration {
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = time.Second
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	backoff := min
+	for i := 0; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+func (p *RetryPolicy) methodRetryable(method string) bool {
+	if p.RetryAllMethods {
+		return true
+	}
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// Reads the Retry-After header of a response, which holds either a number of
+// seconds or an HTTP date.
+func retryAfter(r *http.Response) (time.Duration, bool) {
+	value := r.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Reports whether the API turned a request away for tripping one of its
+// secondary (formerly "abuse detection") rate limits.
+func isSecondaryRateLimit(e *ErrorResponse) bool {
+	switch e.Response.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return false
+	}
+	message := strings.ToLower(e.Message)
+	return strings.Contains(message, "secondary rate limit") ||
+		strings.Contains(message, "abuse detection")
+}
+
+// Sleeps for the given duration, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,252 @@
+package gothub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fake GitHub that fails the first few requests to every path, by calling
+// the given function, before succeeding.
+type flakyServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string]int
+	bodies   []string
+}
+
+// Starts a flakyServer, and returns it along with a session pointed at it,
+// made with the given options as well.
+func newFlakyServer(t *testing.T, failures int, fail func(w http.ResponseWriter), opts ...Option) (*GitHub, *flakyServer) {
+	s := &flakyServer{requests: make(map[string]int)}
+	g, server := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		n := s.requests[r.URL.Path]
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if n <= failures {
+			fail(w)
+			return
+		}
+		if r.Method == "POST" {
+			w.Header().Set("Location", fmt.Sprintf("%s/user/keys/7", s.URL))
+			w.WriteHeader(http.StatusCreated)
+		}
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}, opts...)
+	s.Server = server
+	return g, s
+}
+
+func serverError(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprint(w, `{"message": "Service Unavailable"}`)
+}
+
+func secondaryRateLimit(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "0")
+	w.WriteHeader(http.StatusForbidden)
+	fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`)
+}
+
+var fastRetries = RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+func TestRetryServerErrors(t *testing.T) {
+	var events []RetryEvent
+	policy := fastRetries
+	policy.OnRetry = func(e RetryEvent) { events = append(events, e) }
+	g, server := newFlakyServer(t, 2, serverError, WithRetryPolicy(policy))
+
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if server.requests["/users/octocat"] != 3 {
+		t.Errorf("Expected 3 attempts, got %d", server.requests["/users/octocat"])
+	}
+	if len(events) != 2 || events[0].Attempt != 1 || events[1].Attempt != 2 {
+		t.Fatalf("Unexpected retry events %+v", events)
+	}
+	if events[0].Response.StatusCode != http.StatusServiceUnavailable || events[0].Err == nil {
+		t.Errorf("The retry event should describe the failed attempt: %+v", events[0])
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	g, server := newFlakyServer(t, 10, serverError, WithRetryPolicy(fastRetries))
+
+	var e *ErrorResponse
+	if _, _, err := g.GetUser("octocat"); !errors.As(err, &e) || e.Response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last HTTP 503 to be returned, got %v", err)
+	}
+	if server.requests["/users/octocat"] != 4 {
+		t.Errorf("Expected 4 attempts, got %d", server.requests["/users/octocat"])
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	g, _ := newFlakyServer(t, 1, serverError, WithRetryPolicy(fastRetries))
+	if _, _, err := g.AddPublicKey("laptop", "ssh-rsa AAAA"); err == nil {
+		t.Error("A POST should not be retried after a server error by default")
+	}
+
+	policy := fastRetries
+	policy.RetryAllMethods = true
+	g, server := newFlakyServer(t, 1, serverError, WithRetryPolicy(policy))
+
+	if id, _, err := g.AddPublicKey("laptop", "ssh-rsa AAAA"); err != nil {
+		t.Fatal(err)
+	} else if id != 7 {
+		t.Errorf("Expected key 7, got %d", id)
+	}
+	if len(server.bodies) != 2 || server.bodies[0] == "" || server.bodies[0] != server.bodies[1] {
+		t.Errorf("The request body was not replayed: %q", server.bodies)
+	}
+}
+
+func TestRetryAfterServerError(t *testing.T) {
+	g, server := newFlakyServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "0")
+		serverError(w)
+	}, WithRetryPolicy(fastRetries))
+	if _, _, err := g.AddPublicKey("laptop", "ssh-rsa AAAA"); err == nil {
+		t.Error("A POST should not be retried after a server error, even with a Retry-After header")
+	}
+	if len(server.bodies) != 1 {
+		t.Errorf("Expected a single attempt, got %d", len(server.bodies))
+	}
+
+	// An idempotent request is, but not for as long as a bogus header asks.
+	var waited time.Duration
+	policy := fastRetries
+	policy.SecondaryRateLimitWait = 10 * time.Millisecond
+	policy.OnRetry = func(e RetryEvent) { waited = e.Wait }
+	g, _ = newFlakyServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "86400")
+		serverError(w)
+	}, WithRetryPolicy(policy))
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if waited != 10*time.Millisecond {
+		t.Errorf("Expected the wait to be capped at 10ms, waited %s", waited)
+	}
+}
+
+func TestRetrySecondaryRateLimit(t *testing.T) {
+	g, server := newFlakyServer(t, 1, secondaryRateLimit, WithRetryPolicy(fastRetries))
+
+	// The request was never acted upon, so even a POST is tried again.
+	if _, _, err := g.AddPublicKey("laptop", "ssh-rsa AAAA"); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.bodies) != 2 || server.bodies[0] != server.bodies[1] {
+		t.Errorf("The request body was not replayed: %q", server.bodies)
+	}
+
+	// Without a Retry-After header, the policy's wait is used.
+	var waited time.Duration
+	policy := fastRetries
+	policy.SecondaryRateLimitWait = 20 * time.Millisecond
+	policy.OnRetry = func(e RetryEvent) { waited = e.Wait }
+	g, _ = newFlakyServer(t, 1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "You have triggered an abuse detection mechanism."}`)
+	}, WithRetryPolicy(policy))
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if waited != 20*time.Millisecond {
+		t.Errorf("Expected to wait 20ms, waited %s", waited)
+	}
+}
+
+func TestRetryPrimaryRateLimit(t *testing.T) {
+	g, server := newFlakyServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+	}, WithRetryPolicy(fastRetries))
+	if _, _, err := g.GetUser("octocat"); !IsRateLimit(err) {
+		t.Errorf("Expected a rate limit error, got %v", err)
+	}
+	if server.requests["/users/octocat"] != 1 {
+		t.Errorf("Running out of calls should not be retried")
+	}
+}
+
+func TestRetryTransportErrors(t *testing.T) {
+	attempts := 0
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return jsonResponse(r, http.StatusOK, `{"login": "octocat"}`), nil
+	})
+
+	g, err := New(WithTransport(transport), WithRetryPolicy(fastRetries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.GetUser("octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxRetries: 3, MinBackoff: time.Hour}
+	policy.OnRetry = func(RetryEvent) { cancel() }
+	g, _ := newFlakyServer(t, 10, serverError, WithRetryPolicy(policy))
+	if _, _, err := g.GetUserContext(ctx, "octocat"); err != context.Canceled {
+		t.Errorf("Expected the backoff to be cut short, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := make(http.Header)
+	r := &http.Response{Header: header}
+
+	if _, ok := retryAfter(r); ok {
+		t.Error("A missing Retry-After header should be ignored")
+	}
+
+	header.Set("Retry-After", "120")
+	if wait, ok := retryAfter(r); !ok || wait != 2*time.Minute {
+		t.Errorf("Expected 2m, got %s", wait)
+	}
+
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if wait, ok := retryAfter(r); !ok || wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("Expected about an hour, got %s", wait)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	bounds := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for attempt, max := range bounds {
+		for i := 0; i < 20; i++ {
+			if wait := policy.exponential(attempt); wait < max/2 || wait > max {
+				t.Errorf("Attempt %d: %s is outside of [%s, %s]", attempt, wait, max/2, max)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package gothub
+
+import "strings"
+
+// The OAuth scopes that implicitly grant other scopes, as documented here:
+// https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/scopes-for-oauth-apps
+var impliedScopes = map[string][]string{
+	"repo":             {"repo:status", "repo_deployment", "public_repo", "repo:invite", "security_events"},
+	"admin:org":        {"write:org", "read:org"},
+	"write:org":        {"read:org"},
+	"admin:public_key": {"write:public_key", "read:public_key"},
+	"write:public_key": {"read:public_key"},
+	"admin:repo_hook":  {"write:repo_hook", "read:repo_hook"},
+	"write:repo_hook":  {"read:repo_hook"},
+	"admin:gpg_key":    {"write:gpg_key", "read:gpg_key"},
+	"write:gpg_key":    {"read:gpg_key"},
+	"user":             {"read:user", "user:email", "user:follow"},
+	"write:packages":   {"read:packages"},
+	"write:discussion": {"read:discussion"},
+	"project":          {"read:project"},
+}
+
+// Splits the comma-separated value of an X-OAuth-Scopes or
+// X-Accepted-OAuth-Scopes header into its scopes.
+func parseScopes(header string) []string {
+	scopes := make([]string, 0)
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// Reports whether the session's token was granted the given OAuth scope,
+// either directly or through a broader scope (e.g. "repo" grants
+// "public_repo").
+//
+// The granted scopes are only known once the API has responded to a request
+// made with the token; TokenLogin and BearerLogin make one such request.
+func (g *GitHub) HasScope(scope string) bool {
+	scopes, _ := g.OAuthScopes()
+	for _, granted := range scopes {
+		if granted == scope || impliesScope(granted, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the OAuth scopes granted to the session's token, and the scopes that
+// the most recently called endpoint accepts; see the Scopes and AcceptedScopes
+// fields.
+func (g *GitHub) OAuthScopes() (scopes, accepted []string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Scopes, g.AcceptedScopes
+}
+
+func impliesScope(granted, scope string) bool {
+	for _, implied := range impliedScopes[granted] {
+		if implied == scope || impliesScope(implied, scope) {
+			return true
+		}
+	}
+	return false
+}
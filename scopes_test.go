@@ -0,0 +1,89 @@
+package gothub
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := map[string][]string{
+		"":                       {},
+		"repo":                   {"repo"},
+		"repo, user, admin:org":  {"repo", "user", "admin:org"},
+		" gist ,, read:packages": {"gist", "read:packages"},
+	}
+	for header, expected := range tests {
+		if scopes := parseScopes(header); !reflect.DeepEqual(scopes, expected) {
+			t.Errorf("parseScopes(%q) = %q, expected %q", header, scopes, expected)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	g := &GitHub{Scopes: []string{"repo", "admin:org", "gist"}}
+
+	for _, scope := range []string{"repo", "public_repo", "repo:status", "write:org", "read:org", "gist"} {
+		if !g.HasScope(scope) {
+			t.Errorf("Expected the token to have the %q scope", scope)
+		}
+	}
+	for _, scope := range []string{"user", "user:email", "delete_repo", "admin:public_key"} {
+		if g.HasScope(scope) {
+			t.Errorf("Did not expect the token to have the %q scope", scope)
+		}
+	}
+}
+
+func TestTokenLoginScopes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "token ghp_pat", "Bearer gho_oauth":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/user/keys":
+			w.Header().Set("X-Accepted-OAuth-Scopes", "admin:public_key, write:public_key, read:public_key")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	for _, opt := range []Option{WithToken("ghp_pat"), WithBearerToken("gho_oauth")} {
+		g, err := login(WithBaseUrl(server.URL, ""), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(g.Scopes, []string{"repo", "read:org"}) {
+			t.Errorf("Unexpected scopes %q", g.Scopes)
+		}
+		if g.HasScope("write:org") {
+			t.Error("read:org should not grant write:org")
+		}
+
+		var keys []PublicKey
+		if _, err := g.Do(&keys, "GET", "user", "keys"); err != nil {
+			t.Fatal(err)
+		}
+		if len(g.AcceptedScopes) != 3 || g.AcceptedScopes[0] != "admin:public_key" {
+			t.Errorf("Unexpected accepted scopes %q", g.AcceptedScopes)
+		}
+	}
+
+	if _, err := login(WithBaseUrl(server.URL, ""), WithToken("expired")); err == nil {
+		t.Error("Logging in with a bad token should fail")
+	}
+	if _, err := New(WithToken("")); err == nil {
+		t.Error("An empty token should be rejected")
+	}
+}
@@ -1,6 +1,7 @@
 package gothub
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -217,53 +218,77 @@ type EmailSearchResult struct {
 }
 
 // Find issues by state and keyword.
-func (g *GitHub) SearchIssues(p *IssuesSearchParam) ([]*IssuesSearchResult, error) {
+func (g *GitHub) SearchIssues(p *IssuesSearchParam) ([]*IssuesSearchResult, *Response, error) {
+	return g.SearchIssuesContext(context.Background(), p)
+}
+
+// Like SearchIssues, but the request is bound to ctx.
+func (g *GitHub) SearchIssuesContext(ctx context.Context, p *IssuesSearchParam) ([]*IssuesSearchResult, *Response, error) {
 	addr, err := p.addr()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if err := g.callGithubApi("GET", addr, &p.result); err != nil {
-		return nil, err
+	resp, err := g.callGithubApi(ctx, "GET", addr, &p.result)
+	if err != nil {
+		return nil, resp, err
 	}
-	return p.result.Issues, nil
+	return p.result.Issues, resp, nil
 }
 
 // Find repositories by keyword.
 // This method returns up to 100 results per page and
 // pages can be fetched using the start_page parameter.
-func (g *GitHub) SearchRepositories(p *RepositoriesSearchParam) ([]*RepositoriesSearchResult, error) {
+func (g *GitHub) SearchRepositories(p *RepositoriesSearchParam) ([]*RepositoriesSearchResult, *Response, error) {
+	return g.SearchRepositoriesContext(context.Background(), p)
+}
+
+// Like SearchRepositories, but the request is bound to ctx.
+func (g *GitHub) SearchRepositoriesContext(ctx context.Context, p *RepositoriesSearchParam) ([]*RepositoriesSearchResult, *Response, error) {
 	addr, err := p.addr()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if err := g.callGithubApi("GET", addr, &p.result); err != nil {
-		return nil, err
+	resp, err := g.callGithubApi(ctx, "GET", addr, &p.result)
+	if err != nil {
+		return nil, resp, err
 	}
-	return p.result.Repositories, nil
+	return p.result.Repositories, resp, nil
 }
 
 // Find users by state and keyword.
-func (g *GitHub) SearchUsers(p *UsersSearchParam) ([]*UsersSearchResult, error) {
+func (g *GitHub) SearchUsers(p *UsersSearchParam) ([]*UsersSearchResult, *Response, error) {
+	return g.SearchUsersContext(context.Background(), p)
+}
+
+// Like SearchUsers, but the request is bound to ctx.
+func (g *GitHub) SearchUsersContext(ctx context.Context, p *UsersSearchParam) ([]*UsersSearchResult, *Response, error) {
 	addr, err := p.addr()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if err := g.callGithubApi("GET", addr, &p.result); err != nil {
-		return nil, err
+	resp, err := g.callGithubApi(ctx, "GET", addr, &p.result)
+	if err != nil {
+		return nil, resp, err
 	}
-	return p.result.Users, nil
+	return p.result.Users, resp, nil
 }
 
 // Find user by email address.
 // This API call is added for compatibility reasons only.
 // There’s no guarantee that full email searches will always be available.
-func (g *GitHub) SearchEmail(p *EmailSearchParam) (*EmailSearchResult, error) {
+func (g *GitHub) SearchEmail(p *EmailSearchParam) (*EmailSearchResult, *Response, error) {
+	return g.SearchEmailContext(context.Background(), p)
+}
+
+// Like SearchEmail, but the request is bound to ctx.
+func (g *GitHub) SearchEmailContext(ctx context.Context, p *EmailSearchParam) (*EmailSearchResult, *Response, error) {
 	addr, err := p.addr()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if err := g.callGithubApi("GET", addr, &p.result); err != nil {
-		return nil, err
+	resp, err := g.callGithubApi(ctx, "GET", addr, &p.result)
+	if err != nil {
+		return nil, resp, err
 	}
-	return p.result.User, nil
+	return p.result.User, resp, nil
 }
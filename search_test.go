@@ -1,28 +1,10 @@
 package gothub
 
-import (
-	"log"
-	"testing"
-)
-
-var (
-	tgh *GitHub
-)
-
-func init() {
-	username, password, err := getTestingCredentials()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	tgh, err = BasicLogin(username, password)
-	if err != nil {
-		log.Fatal("Failed to login to the Github")
-	}
-}
+import "testing"
 
 func TestSearchIssues(t *testing.T) {
-	rs, err := tgh.SearchIssues(&IssuesSearchParam{
+	tgh := replaySession(t)
+	rs, _, err := tgh.SearchIssues(&IssuesSearchParam{
 		Owner:      "nesv",
 		Repository: "gothub",
 		State:      "open",
@@ -38,7 +20,8 @@ func TestSearchIssues(t *testing.T) {
 }
 
 func TestSearchRepositories(t *testing.T) {
-	rs, err := tgh.SearchRepositories(&RepositoriesSearchParam{
+	tgh := replaySession(t)
+	rs, _, err := tgh.SearchRepositories(&RepositoriesSearchParam{
 		Keyword: "whac a gopher",
 	})
 	if err != nil {
@@ -51,7 +34,8 @@ func TestSearchRepositories(t *testing.T) {
 }
 
 func TestSearchUsers(t *testing.T) {
-	rs, err := tgh.SearchUsers(&UsersSearchParam{
+	tgh := replaySession(t)
+	rs, _, err := tgh.SearchUsers(&UsersSearchParam{
 		Keyword: "Homin Lee",
 	})
 	if err != nil {
@@ -64,7 +48,8 @@ func TestSearchUsers(t *testing.T) {
 }
 
 func TestSearchEmail(t *testing.T) {
-	r, err := tgh.SearchEmail(&EmailSearchParam{
+	tgh := replaySession(t)
+	r, _, err := tgh.SearchEmail(&EmailSearchParam{
 		Email: "octocat@github.com",
 	})
 	if err != nil {
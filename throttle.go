@@ -0,0 +1,211 @@
+package gothub
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Holds back a session's requests, to keep clear of GitHub's secondary rate
+// limits, as described here:
+// https://docs.github.com/en/rest/using-the-rest-api/best-practices-for-using-the-rest-api
+//
+// The zero values of the fields leave their limit off.
+type Throttle struct {
+	// How many requests may be waiting for their response at once.
+	MaxConcurrent int
+
+	// How many requests may be sent per second, on average, and how many of
+	// them may be sent at once after a lull. Burst defaults to one.
+	RequestsPerSecond float64
+	Burst             int
+
+	// Send POST, PATCH, PUT and DELETE requests one at a time, as GitHub
+	// recommends. GraphQL queries are POSTed too, but only mutations are
+	// held back.
+	SerializeMutations bool
+}
+
+// How much a session's requests are being held back by its Throttle.
+type ThrottleStats struct {
+	// How many requests are waiting for their response, and how many are
+	// waiting to be sent.
+	InFlight int
+	Waiting  int
+
+	// How many requests have had to wait, and how long they have waited, in
+	// total.
+	Delayed int64
+	Waited  time.Duration
+}
+
+type throttle struct {
+	slots     chan struct{}
+	mutations chan struct{}
+
+	rate   float64
+	burst  float64
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	inFlight atomic.Int64
+	waiting  atomic.Int64
+	delayed  atomic.Int64
+	waited   atomic.Int64
+}
+
+func newThrottle(t Throttle) *throttle {
+	th := &throttle{rate: t.RequestsPerSecond, burst: float64(t.Burst)}
+	if t.MaxConcurrent > 0 {
+		th.slots = make(chan struct{}, t.MaxConcurrent)
+	}
+	if t.SerializeMutations {
+		th.mutations = make(chan struct{}, 1)
+	}
+	if th.burst < 1 {
+		th.burst = 1
+	}
+	th.tokens = th.burst
+	return th
+}
+
+// Reports whether the request changes anything. GraphQL queries are POSTed,
+// but only read.
+func isMutation(req *http.Request) bool {
+	switch req.Method {
+	case "POST", "PATCH", "PUT", "DELETE":
+		query, _ := req.Context().Value(graphqlQueryKey{}).(bool)
+		return !query
+	}
+	return false
+}
+
+// Waits until the request may be sent, or until ctx is done, and returns how
+// long that took, if it had to wait at all. The returned function has to be
+// called once the response has come in (or the request has failed).
+func (t *throttle) acquire(ctx context.Context, mutation bool) (release func(), waited time.Duration, err error) {
+	if t == nil {
+		return func() {}, 0, nil
+	}
+
+	start := time.Now()
+	blocked := false
+	t.waiting.Add(1)
+	defer t.waiting.Add(-1)
+
+	// Mutations queue up for their turn before taking a slot, so that they
+	// don't keep other requests from going out while they wait.
+	queues := []chan struct{}{t.slots}
+	if mutation {
+		queues = []chan struct{}{t.mutations, t.slots}
+	}
+
+	var held []chan struct{}
+	releaseAll := func() {
+		for _, ch := range held {
+			<-ch
+		}
+	}
+	for _, ch := range queues {
+		if ch == nil {
+			continue
+		}
+		select {
+		case ch <- struct{}{}:
+		default:
+			blocked = true
+			select {
+			case ch <- struct{}{}:
+			case <-ctx.Done():
+				releaseAll()
+				t.delay(start)
+				return nil, 0, ctx.Err()
+			}
+		}
+		held = append(held, ch)
+	}
+
+	slept, err := t.wait(ctx)
+	if err != nil {
+		releaseAll()
+		if blocked || slept {
+			t.delay(start)
+		}
+		return nil, 0, err
+	}
+	if blocked || slept {
+		waited = t.delay(start)
+	}
+
+	t.inFlight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.inFlight.Add(-1)
+			releaseAll()
+		})
+	}, waited, nil
+}
+
+// Counts a request that has been held back since start, and returns for how
+// long.
+func (t *throttle) delay(start time.Time) time.Duration {
+	waited := time.Since(start)
+	t.delayed.Add(1)
+	t.waited.Add(int64(waited))
+	return waited
+}
+
+// Takes a token from the bucket that refills at RequestsPerSecond, sleeping
+// until there is one.
+func (t *throttle) wait(ctx context.Context) (slept bool, err error) {
+	if t.rate <= 0 {
+		return false, nil
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	if !t.last.IsZero() {
+		t.tokens += now.Sub(t.last).Seconds() * t.rate
+		if t.tokens > t.burst {
+			t.tokens = t.burst
+		}
+	}
+	t.last = now
+	t.tokens--
+	var d time.Duration
+	if t.tokens < 0 {
+		d = time.Duration(-t.tokens / t.rate * float64(time.Second))
+	}
+	t.mu.Unlock()
+
+	if d <= 0 {
+		return false, nil
+	}
+	if err := sleep(ctx, d); err != nil {
+		// The token was never used, so it goes back.
+		t.mu.Lock()
+		t.tokens++
+		t.mu.Unlock()
+		return true, err
+	}
+	return true, nil
+}
+
+// Returns how much the session's requests are being held back, if it was
+// given a Throttle.
+func (g *GitHub) ThrottleStats() ThrottleStats {
+	t := g.throttle
+	if t == nil {
+		return ThrottleStats{}
+	}
+	return ThrottleStats{
+		InFlight: int(t.inFlight.Load()),
+		Waiting:  int(t.waiting.Load()),
+		Delayed:  t.delayed.Load(),
+		Waited:   time.Duration(t.waited.Load()),
+	}
+}
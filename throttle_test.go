@@ -0,0 +1,93 @@
+package gothub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Answers requests like a GitHub that takes a while to answer, and keeps track
+// of the most requests (and mutations) it has had to answer at once.
+func slowHandler(delay time.Duration) (http.HandlerFunc, *atomic.Int64, *atomic.Int64) {
+	var inFlight, mutating, maxInFlight, maxMutating atomic.Int64
+	track := func(n, max *atomic.Int64) {
+		current := n.Add(1)
+		for {
+			m := max.Load()
+			if current <= m || max.CompareAndSwap(m, current) {
+				return
+			}
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		track(&inFlight, &maxInFlight)
+		defer inFlight.Add(-1)
+		if r.Method != "GET" {
+			track(&mutating, &maxMutating)
+			defer mutating.Add(-1)
+		}
+
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}, &maxInFlight, &maxMutating
+}
+
+func TestThrottleConcurrency(t *testing.T) {
+	handler, maxInFlight, maxMutating := slowHandler(20 * time.Millisecond)
+	g, _ := newTestSession(t, handler, WithThrottle(Throttle{MaxConcurrent: 3, SerializeMutations: true}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			method := "GET"
+			if i%2 == 0 {
+				method = "PATCH"
+			}
+			if _, err := g.DoRequest(context.Background(), nil, method, "/user", nil, nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := maxInFlight.Load(); n > 3 {
+		t.Errorf("Expected at most 3 requests at once, got %d", n)
+	}
+	if n := maxMutating.Load(); n != 1 {
+		t.Errorf("Expected mutations to go one at a time, got %d", n)
+	}
+	stats := g.ThrottleStats()
+	if stats.InFlight != 0 || stats.Waiting != 0 || stats.Delayed == 0 || stats.Waited <= 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestThrottleGraphQLQueries(t *testing.T) {
+	handler, _, maxPosting := slowHandler(20 * time.Millisecond)
+	g, _ := newTestSession(t, handler, WithThrottle(Throttle{SerializeMutations: true}))
+
+	// GraphQL queries are POSTed, but do not wait for each other the way
+	// mutations do.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.GraphQL(context.Background(), nil, "query { viewer { login } }", nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := maxPosting.Load(); n < 2 {
+		t.Errorf("Expected GraphQL queries to go out together, got %d at most", n)
+	}
+}
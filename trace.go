@@ -0,0 +1,212 @@
+package gothub
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nesv/gothub/internal/redact"
+)
+
+// How much of a body goes into a TraceEvent.
+const maxTraceBody = 64 << 10
+
+// What a TraceEvent puts in place of credentials.
+const redacted = redact.Placeholder
+
+// Is told about every request a session sends (every attempt, when requests
+// are retried), once the response to it has come back or the request has
+// failed. Set one up with WithTracer.
+//
+// Trace is called from the goroutine that made the request, so it must be safe
+// for concurrent use if the session is shared.
+type Tracer interface {
+	Trace(ctx context.Context, e *TraceEvent)
+}
+
+// Adapts an ordinary function to the Tracer interface.
+type TracerFunc func(ctx context.Context, e *TraceEvent)
+
+func (f TracerFunc) Trace(ctx context.Context, e *TraceEvent) {
+	f(ctx, e)
+}
+
+// Describes a request that a session sent, and what came of it. Credentials in
+// the URL, the headers, the error and the bodies' token fields have been
+// redacted; the bodies are only there when the session was set up to trace
+// them.
+type TraceEvent struct {
+	Method        string
+	Url           string
+	RequestHeader http.Header
+	RequestBody   []byte
+
+	// The status code and headers of the response; zero and nil when the
+	// request could not be sent.
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+
+	// The rate limit and request ID the response reported.
+	Rate      Rate
+	RequestId string
+
+	// How long it took for the response to come back, and which attempt at
+	// the request this was, starting at 1.
+	Latency time.Duration
+	Attempt int
+
+	// Why the request failed, if it did.
+	Err error
+}
+
+// Builds the TraceEvent for a request, and hands it to the session's tracer.
+func (g *GitHub) trace(req *http.Request, requestBody []byte, response *http.Response, err error, latency time.Duration, attempt int) {
+	e := &TraceEvent{
+		Method:        req.Method,
+		Url:           redact.Url(req.URL),
+		RequestHeader: redact.Header(req.Header),
+		RequestBody:   redact.Body(requestBody),
+		Latency:       latency,
+		Attempt:       attempt,
+		Err:           redactError(err, req.URL),
+	}
+	if response != nil {
+		e.StatusCode = response.StatusCode
+		e.ResponseHeader = redact.Header(response.Header)
+		e.Rate, _ = parseRate(response.Header)
+		e.RequestId = response.Header.Get("X-GitHub-Request-Id")
+		if g.traceBodies {
+			e.ResponseBody = redact.Body(peekResponseBody(response))
+		}
+	}
+	g.tracer.Trace(req.Context(), e)
+}
+
+// An error whose message has had the credentials in a URL redacted from it,
+// but which still unwraps to the original error (whose message has not).
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Redacts the credentials in the URL from the error's message, as they turn up
+// in the messages of *ErrorResponse and *url.Error.
+func redactError(err error, u *url.URL) error {
+	if err == nil {
+		return nil
+	}
+	// The HTTP client masks the password of URLs in its errors, but not the
+	// user name, which may just as well be a token.
+	msg, clean := err.Error(), redact.Url(u)
+	for _, raw := range []string{u.String(), u.Redacted()} {
+		if raw != clean {
+			msg = strings.ReplaceAll(msg, raw, clean)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
+
+// Returns (the start of) the body of the request, without using it up.
+func peekRequestBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	b, _ := ioutil.ReadAll(io.LimitReader(body, maxTraceBody))
+	return b
+}
+
+// Returns (the start of) the body of the response, leaving it to be read again.
+func peekResponseBody(response *http.Response) []byte {
+	if response.Body == nil {
+		return nil
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(response.Body, maxTraceBody))
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), response.Body), response.Body}
+	return b
+}
+
+// A Tracer that writes its events to a structured logger. Successful requests
+// are logged at the debug level, and failed ones at the warning level, so a
+// logger set up for "info" only hears about failures:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+//	g, err := gothub.New(gothub.WithTracer(gothub.SlogTracer{Logger: logger}, false))
+type SlogTracer struct {
+	// The logger to write to; slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+func (t SlogTracer) Trace(ctx context.Context, e *TraceEvent) {
+	logger := t.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	level := slog.LevelDebug
+	if e.Err != nil {
+		level = slog.LevelWarn
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", e.Method),
+		slog.String("url", e.Url),
+		slog.Int("status", e.StatusCode),
+		slog.Duration("latency", e.Latency),
+		slog.Int("attempt", e.Attempt),
+	}
+	if e.RequestId != "" {
+		attrs = append(attrs, slog.String("request_id", e.RequestId))
+	}
+	if e.Rate.Limit > 0 {
+		attrs = append(attrs, slog.Group("rate",
+			slog.Int("limit", e.Rate.Limit),
+			slog.Int("remaining", e.Rate.Remaining),
+			slog.Int("used", e.Rate.Used),
+			slog.Time("reset", e.Rate.Reset),
+		))
+	}
+	if e.RequestHeader != nil {
+		attrs = append(attrs, slog.Any("request_header", e.RequestHeader))
+	}
+	if e.RequestBody != nil {
+		attrs = append(attrs, slog.String("request_body", string(e.RequestBody)))
+	}
+	if e.ResponseBody != nil {
+		attrs = append(attrs, slog.String("response_body", string(e.ResponseBody)))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+
+	logger.LogAttrs(ctx, level, "GitHub API request", attrs...)
+}
@@ -2,6 +2,7 @@ package gothub
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +10,6 @@ import (
 	"net/http"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -62,36 +62,35 @@ type Follower struct {
 }
 
 // Gets a detailed list of a user's followers.
-func (u User) GetFollowers() (followers []Follower, err error) {
-	uri := fmt.Sprintf("/users/%s/followers", u.Login)
-	fs := make([]Follower, 0)
-	err = u.g.callGithubApi("GET", uri, &fs)
-	if err != nil {
-		return
-	}
-	/*
-		if len(response.Header.Get("Link")) > 0 {
-			// It would appear that we have to make several more requests, so as 
-			// to get all of the user's followers (read: pagination).
-			pages := parseLinkHeader(response)
-			for _, page := range pages {
-				url, _ := url.Parse(page.Url)
-				f := make([]Follower, 0)
-				response, err = u.g.callGithubApi("GET", url.RequestURI(), &f)
-				fs = append(fs, f...)
-			}
-		}
-	*/
-	followers = fs
-	return
+func (u *User) GetFollowers() (followers []Follower, resp *Response, err error) {
+	return u.GetFollowersContext(context.Background())
+}
+
+// Like GetFollowers, but the request is bound to ctx.
+func (u *User) GetFollowersContext(ctx context.Context) (followers []Follower, resp *Response, err error) {
+	return listAll(ctx, u.ListFollowers(nil))
+}
+
+// Returns an iterator over the user's followers, starting at the page
+// selected by opts (which may be nil).
+func (u *User) ListFollowers(opts *ListOptions) *ListIterator[Follower] {
+	return newListIterator[Follower](u.g, fmt.Sprintf("/users/%s/followers", u.Login), opts)
 }
 
 // Gets a list of users the user is following.
-func (u User) GetFollowing() (following []Follower, err error) {
-	uri := fmt.Sprintf("/users/%s/following", u.Login)
-	following = make([]Follower, 0)
-	err = u.g.callGithubApi("GET", uri, &following)
-	return
+func (u *User) GetFollowing() (following []Follower, resp *Response, err error) {
+	return u.GetFollowingContext(context.Background())
+}
+
+// Like GetFollowing, but the request is bound to ctx.
+func (u *User) GetFollowingContext(ctx context.Context) (following []Follower, resp *Response, err error) {
+	return listAll(ctx, u.ListFollowing(nil))
+}
+
+// Returns an iterator over the users the user is following, starting at the
+// page selected by opts (which may be nil).
+func (u *User) ListFollowing(opts *ListOptions) *ListIterator[Follower] {
+	return newListIterator[Follower](u.g, fmt.Sprintf("/users/%s/following", u.Login), opts)
 }
 
 // Holds information about user's public SSH keys that they have provided to GitHub.
@@ -103,11 +102,19 @@ type PublicKey struct {
 }
 
 // Gets the verified public SSH keys for a user.
-func (u User) GetPublicKeys() (keys []PublicKey, err error) {
-	uri := fmt.Sprintf("/users/%s/keys", u.Login)
-	keys = make([]PublicKey, 0)
-	err = u.g.callGithubApi("GET", uri, &keys)
-	return
+func (u *User) GetPublicKeys() (keys []PublicKey, resp *Response, err error) {
+	return u.GetPublicKeysContext(context.Background())
+}
+
+// Like GetPublicKeys, but the request is bound to ctx.
+func (u *User) GetPublicKeysContext(ctx context.Context) (keys []PublicKey, resp *Response, err error) {
+	return listAll(ctx, u.ListPublicKeys(nil))
+}
+
+// Returns an iterator over the user's verified public SSH keys, starting at
+// the page selected by opts (which may be nil).
+func (u *User) ListPublicKeys(opts *ListOptions) *ListIterator[PublicKey] {
+	return newListIterator[PublicKey](u.g, fmt.Sprintf("/users/%s/keys", u.Login), opts)
 }
 
 // Returns the details of a single user, as specified by their "login".
@@ -115,134 +122,188 @@ func (u User) GetPublicKeys() (keys []PublicKey, err error) {
 // The term "login" is synonymous with "username":
 //
 //    https://github.com/<login>
-func (g *GitHub) GetUser(login string) (*User, error) {
+func (g *GitHub) GetUser(login string) (*User, *Response, error) {
+	return g.GetUserContext(context.Background(), login)
+}
+
+// Like GetUser, but the request is bound to ctx.
+func (g *GitHub) GetUserContext(ctx context.Context, login string) (*User, *Response, error) {
 	var user User
-	err := g.callGithubApi("GET", fmt.Sprintf("/users/%s", login), &user)
+	resp, err := g.callGithubApi(ctx, "GET", fmt.Sprintf("/users/%s", login), &user)
 	if err != nil {
-		return nil, err
+		return nil, resp, err
 	}
 	user.g = g
-	return &user, nil
+	return &user, resp, nil
 }
 
 // Returns the currently-authenticated user, as a pointer to a User struct.
-func (g *GitHub) GetCurrentUser() (*User, error) {
+func (g *GitHub) GetCurrentUser() (*User, *Response, error) {
+	return g.GetCurrentUserContext(context.Background())
+}
+
+// Like GetCurrentUser, but the request is bound to ctx.
+func (g *GitHub) GetCurrentUserContext(ctx context.Context) (*User, *Response, error) {
 	var user User
-	err := g.callGithubApi("GET", "/user", &user)
+	resp, err := g.callGithubApi(ctx, "GET", "/user", &user)
 	if err != nil {
-		return nil, err
+		return nil, resp, err
 	}
 	user.g = g
-	return &user, nil
+	return &user, resp, nil
 }
 
 // Returns a list of the email accounts associated with the currently-
 // authenticated user.
-func (g *GitHub) Emails() (emails []string, err error) {
-	response, err := call(g, "GET", "/user/emails")
+func (g *GitHub) Emails() (emails []string, resp *Response, err error) {
+	return g.EmailsContext(context.Background())
+}
+
+// Like Emails, but the request is bound to ctx.
+func (g *GitHub) EmailsContext(ctx context.Context) (emails []string, resp *Response, err error) {
+	response, err := call(ctx, g, "GET", "/user/emails")
+	resp = newResponse(response)
 	if err != nil {
 		return
 	}
+	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		return
 	}
-
-	rs := strings.Trim(string(body), "[]")
-	for _, email := range strings.Split(rs, ",") {
-		emails = append(emails, strings.Trim(email, "\""))
-	}
+	err = json.Unmarshal(body, &emails)
 	return
 }
 
 // Associate a list of emails with the currently-authenticated user's account.
-func (g *GitHub) AddEmails(emails []string) (err error) {
-	addresses := fmt.Sprintf("%v", emails)
-	buf := bytes.NewBufferString(addresses)
-	response, err := g.httpPost("/user/emails", nil, buf)
+func (g *GitHub) AddEmails(emails []string) (resp *Response, err error) {
+	return g.AddEmailsContext(context.Background(), emails)
+}
+
+// Like AddEmails, but the request is bound to ctx.
+func (g *GitHub) AddEmailsContext(ctx context.Context, emails []string) (resp *Response, err error) {
+	b, err := json.Marshal(emails)
 	if err != nil {
 		return
 	}
-	if response.StatusCode != http.StatusCreated {
-		e := "GitHub returned a %d status code; was expecting %d"
-		err = errors.New(fmt.Sprintf(e, response.StatusCode, http.StatusCreated))
+	response, err := g.httpPost(ctx, "/user/emails", nil, bytes.NewBuffer(b))
+	resp = newResponse(response)
+	if err != nil {
+		return
 	}
+	defer response.Body.Close()
+	err = checkStatus(response, http.StatusCreated)
 	return
 }
 
 // Disassociate a list of emails from the currently-authenticated user's
 // account.
-func (g *GitHub) DeleteEmails(emails []string) (err error) {
-	addresses := fmt.Sprintf("%v", emails)
-	buf := bytes.NewBufferString(addresses)
-	response, err := g.httpDelete("/user/emails", nil, buf)
+func (g *GitHub) DeleteEmails(emails []string) (resp *Response, err error) {
+	return g.DeleteEmailsContext(context.Background(), emails)
+}
+
+// Like DeleteEmails, but the request is bound to ctx.
+func (g *GitHub) DeleteEmailsContext(ctx context.Context, emails []string) (resp *Response, err error) {
+	b, err := json.Marshal(emails)
 	if err != nil {
 		return
 	}
-	if response.StatusCode != http.StatusNoContent {
-		e := "GitHub returned a %d status code; was expecting %d"
-		err = errors.New(fmt.Sprintf(e, response.StatusCode, http.StatusNoContent))
+	response, err := g.httpDelete(ctx, "/user/emails", nil, bytes.NewBuffer(b))
+	resp = newResponse(response)
+	if err != nil {
+		return
 	}
+	defer response.Body.Close()
+	err = checkStatus(response, http.StatusNoContent)
 	return
 }
 
 // Check to see whether or not the current user `u` is following another user.
-func (g GitHub) IsFollowing(anotherUser string) (following bool, err error) {
+func (g *GitHub) IsFollowing(anotherUser string) (following bool, resp *Response, err error) {
+	return g.IsFollowingContext(context.Background(), anotherUser)
+}
+
+// Like IsFollowing, but the request is bound to ctx.
+func (g *GitHub) IsFollowingContext(ctx context.Context, anotherUser string) (following bool, resp *Response, err error) {
 	uri := fmt.Sprintf("/user/following/%s", anotherUser)
-	response, err := g.httpGet(uri, nil)
-	if err != nil {
+	response, err := g.httpGet(ctx, uri, nil)
+	resp = newResponse(response)
+	if IsNotFound(err) {
+		// GitHub answers with a 404 when the user is not being followed.
+		err = nil
+		return
+	} else if err != nil {
 		return
 	}
-	switch response.StatusCode {
-	case http.StatusNoContent:
-		following = true
-	case http.StatusNotFound:
-		following = false
-	}
+	defer response.Body.Close()
+	err = checkStatus(response, http.StatusNoContent)
+	following = err == nil
 	return
 }
 
 // Follow a user.
-func (g GitHub) Follow(user string) (err error) {
+func (g *GitHub) Follow(user string) (resp *Response, err error) {
+	return g.FollowContext(context.Background(), user)
+}
+
+// Like Follow, but the request is bound to ctx.
+func (g *GitHub) FollowContext(ctx context.Context, user string) (resp *Response, err error) {
 	uri := fmt.Sprintf("/user/following/%s", user)
-	response, err := g.httpPut(uri, nil, nil)
+	response, err := g.httpPut(ctx, uri, nil, nil)
+	resp = newResponse(response)
 	if err != nil {
 		return
 	}
+	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusNoContent {
-		e := "Bad HTTP status; wanted %d got %d"
-		err = errors.New(fmt.Sprintf(e, http.StatusNoContent, response.StatusCode))
-	}
-
+	err = checkStatus(response, http.StatusNoContent)
 	return
 }
 
 // Unfollow a user.
-func (g GitHub) Unfollow(user string) (err error) {
+func (g *GitHub) Unfollow(user string) (resp *Response, err error) {
+	return g.UnfollowContext(context.Background(), user)
+}
+
+// Like Unfollow, but the request is bound to ctx.
+func (g *GitHub) UnfollowContext(ctx context.Context, user string) (resp *Response, err error) {
 	uri := fmt.Sprintf("/user/following/%s", user)
-	response, err := g.httpDelete(uri, nil, nil)
+	response, err := g.httpDelete(ctx, uri, nil, nil)
+	resp = newResponse(response)
 	if err != nil {
 		return
-	} else if response.StatusCode != http.StatusNoContent {
-		e := "Bad HTTP status; wanted %d got %d"
-		err = errors.New(fmt.Sprintf(e, http.StatusNoContent, response.StatusCode))
 	}
+	defer response.Body.Close()
+	err = checkStatus(response, http.StatusNoContent)
 	return
 }
 
 // Fetch a listing of the currently-authenticated user's public SSH keys.
-func (g GitHub) PublicKeys() (keys []PublicKey, err error) {
-	keys = make([]PublicKey, 0)
-	err = g.callGithubApi("GET", "/user/keys", &keys)
-	return
+func (g *GitHub) PublicKeys() (keys []PublicKey, resp *Response, err error) {
+	return g.PublicKeysContext(context.Background())
+}
+
+// Like PublicKeys, but the request is bound to ctx.
+func (g *GitHub) PublicKeysContext(ctx context.Context) (keys []PublicKey, resp *Response, err error) {
+	return listAll(ctx, g.ListPublicKeys(nil))
+}
+
+// Returns an iterator over the currently-authenticated user's public SSH keys,
+// starting at the page selected by opts (which may be nil).
+func (g *GitHub) ListPublicKeys(opts *ListOptions) *ListIterator[PublicKey] {
+	return newListIterator[PublicKey](g, "/user/keys", opts)
 }
 
 // Fetch a singular public SSH key.
-func (g GitHub) GetPublicKey(id int) (key PublicKey, err error) {
+func (g *GitHub) GetPublicKey(id int) (key PublicKey, resp *Response, err error) {
+	return g.GetPublicKeyContext(context.Background(), id)
+}
+
+// Like GetPublicKey, but the request is bound to ctx.
+func (g *GitHub) GetPublicKeyContext(ctx context.Context, id int) (key PublicKey, resp *Response, err error) {
 	uri := fmt.Sprintf("/user/keys/%d", id)
-	err = g.callGithubApi("GET", uri, &key)
+	resp, err = g.callGithubApi(ctx, "GET", uri, &key)
 	return
 }
 
@@ -253,47 +314,56 @@ func (g GitHub) GetPublicKey(id int) (key PublicKey, err error) {
 //
 //     https://api.github.com/user/keys/:id
 //
-func (g GitHub) AddPublicKey(title, key string) (id int, err error) {
+func (g *GitHub) AddPublicKey(title, key string) (id int, resp *Response, err error) {
+	return g.AddPublicKeyContext(context.Background(), title, key)
+}
+
+// Like AddPublicKey, but the request is bound to ctx.
+func (g *GitHub) AddPublicKeyContext(ctx context.Context, title, key string) (id int, resp *Response, err error) {
 	b, err := json.Marshal(PublicKey{Title: title, Key: key})
 	if err != nil {
 		return
 	}
 
+	// A key that already exists is rejected by the API as a validation error;
+	// see IsValidation.
 	buf := bytes.NewBuffer(b)
-	response, err := g.httpPost("/user/keys", nil, buf)
+	response, err := g.httpPost(ctx, "/user/keys", nil, buf)
+	resp = newResponse(response)
 	if err != nil {
 		return
 	}
+	defer response.Body.Close()
+	if err = checkStatus(response, http.StatusCreated); err != nil {
+		return
+	}
 
-	switch response.StatusCode {
-	case 422:
-		err = errors.New("That key already exists")
-
-	case http.StatusCreated:
-		re := regexp.MustCompile(`(\d+)$`)
-		matches := re.FindStringSubmatch(response.Header.Get("Location"))
-		if len(matches) > 1 {
-			id, err = strconv.Atoi(matches[1])
-		} else {
-			e := "Cannot find new pubkey ID in \"%s\""
-			err = errors.New(fmt.Sprintf(e, response.Header.Get("Location")))
-		}
-
-	default:
-		e := "Bad HTTP status; wanted %d got %d"
-		err = errors.New(fmt.Sprintf(e, http.StatusCreated, response.StatusCode))
+	re := regexp.MustCompile(`(\d+)$`)
+	matches := re.FindStringSubmatch(response.Header.Get("Location"))
+	if len(matches) > 1 {
+		id, err = strconv.Atoi(matches[1])
+	} else {
+		e := "Cannot find new pubkey ID in \"%s\""
+		err = errors.New(fmt.Sprintf(e, response.Header.Get("Location")))
 	}
 
 	return
 }
 
 // Removes a public key from your account.
-func (g GitHub) RemovePublicKey(id int) (err error) {
+func (g *GitHub) RemovePublicKey(id int) (resp *Response, err error) {
+	return g.RemovePublicKeyContext(context.Background(), id)
+}
+
+// Like RemovePublicKey, but the request is bound to ctx.
+func (g *GitHub) RemovePublicKeyContext(ctx context.Context, id int) (resp *Response, err error) {
 	uri := fmt.Sprintf("/user/keys/%d", id)
-	response, err := g.httpDelete(uri, nil, nil)
-	if response.StatusCode != http.StatusNoContent {
-		e := "Bad HTTP status; wanted %d got %d"
-		err = errors.New(fmt.Sprintf(e, http.StatusNoContent, response.StatusCode))
+	response, err := g.httpDelete(ctx, uri, nil, nil)
+	resp = newResponse(response)
+	if err != nil {
+		return
 	}
+	defer response.Body.Close()
+	err = checkStatus(response, http.StatusNoContent)
 	return
 }
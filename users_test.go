@@ -1,35 +1,17 @@
 package gothub
 
-import (
-	"log"
-	"testing"
-)
+import "testing"
 
 var (
-	testKeyId   int
-	testSshKeys []string
-	currentUser User
-)
-
-func init() {
-	u, p, err := getTestingCredentials()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	tgh, err = BasicLogin(u, p)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	testSshKeys = []string{
 		"ssh-dss AAAAB3NzaC1kc3MAAACBANTHulCj21/003YdYOqn0mfXc2JtI26haO2z18HqdA4GM6GglTJNepRZnatxH+J7UeQGhA5nChOeBw/pGm3vQ3WxKbrwKl8V2Ag0IdEIRmpc5j3Dx6ihl0jc1D+veVz6xUrqOPzu7YPeDYweUZE6b4L2FQq0Q9QvoVRXlIw1w+9BAAAAFQD32crUBTOaLHglubAGrMpT2irF0QAAAIEAx9E3v1FWFKWXjf3fihBiMfXdON3aOGF1zsH78ZEwXsaxHS9TmuBBClYSSSDzkZPYr0B0lTJgSo6rh9wuIRZul+tKDiNvbND/zl9h1ib2tt3VbfDgJlBQ6NoFt1ZHYZggv7jPogVD+/vRmksjIHp0nejI+EqWB+33gRyge6qu7VsAAACAKO78TWWhCAsGdU2uoGsxlYt9Mj7wphjJxwPvY5RIpT2mfwf0UP0u4R8vospmu9xf3Kqvh4qCztIUIyVGANw55eCzTaKrKFOBkUJqQRKEcpeuePWDIy+MOFWgkFtDbPtVGaziVui5Ujy5anap8EBPb3bFt1cdJioxSLRSREnBMOo= GotHub test key",
 		"ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQCcPo4eTziK61PW+mJVUmUa49r9V731tfwvw/u5LTJ4KZ03+lH5ypxcCQ/32FKvpKRPdLlkgOoj6WxgzwoscqORWrYxmQaOnKiCZuSzCO2ndPgv4/EHQz4VpcxJHJsKIUfeAqjfQDI2WG6LM3iRCc03aIHP/H92tNCX36gX2jyc16mrYjb1+8zMrDLOMv9mPSSjynXqCMoKP7IqsfHfRy+Pd+Knab3nb4VN1ERhSzBdb6Ly8RegZZG3HB4VMheHkld/PcCEky+wVbplA3pirbiL7eMehPCTz8t/cHhwJhoTFlsY3U0CV+7KO6sGPYdas3rgHw8QeyxbFGquwqleJrLr"}
-}
+)
 
 func TestGetUser(t *testing.T) {
+	tgh := replaySession(t)
 	var user User
-	if err := tgh.Do(&user, "GET", "users", "octocat"); err != nil {
+	if _, err := tgh.Do(&user, "GET", "users", "octocat"); err != nil {
 		t.Error(err)
 	} else {
 		t.Logf("ID: %d", user.Id)
@@ -38,7 +20,9 @@ func TestGetUser(t *testing.T) {
 }
 
 func TestGetCurrentUser(t *testing.T) {
-	if err := tgh.Do(&currentUser, "GET", "user"); err != nil {
+	tgh := replaySession(t)
+	var currentUser User
+	if _, err := tgh.Do(&currentUser, "GET", "user"); err != nil {
 		t.Error(err)
 	} else {
 		t.Logf("ID: %d", currentUser.Id)
@@ -47,8 +31,9 @@ func TestGetCurrentUser(t *testing.T) {
 }
 
 func TestUserEmails(t *testing.T) {
+	tgh := replaySession(t)
 	var emails []string
-	if err := tgh.Do(&emails, "GET", "user", "emails"); err != nil {
+	if _, err := tgh.Do(&emails, "GET", "user", "emails"); err != nil {
 		t.Error(err)
 	}
 
@@ -59,8 +44,13 @@ func TestUserEmails(t *testing.T) {
 }
 
 func TestGetFollowers(t *testing.T) {
-	var followers []Follower
-	if err := tgh.Do(&followers, "GET", "users", currentUser.Login, "followers"); err != nil {
+	tgh := replaySession(t)
+	currentUser, _, err := tgh.GetCurrentUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if followers, _, err := currentUser.GetFollowers(); err != nil {
 		t.Error(err)
 	} else {
 		t.Logf("The following users are following \"%s\":", currentUser.Login)
@@ -71,8 +61,13 @@ func TestGetFollowers(t *testing.T) {
 }
 
 func TestGetFollowing(t *testing.T) {
-	var following []Follower
-	if err := tgh.Do(&following, "GET", "users", currentUser.Login, "following"); err != nil {
+	tgh := replaySession(t)
+	currentUser, _, err := tgh.GetCurrentUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if following, _, err := currentUser.GetFollowing(); err != nil {
 		t.Error(err)
 	} else {
 		t.Logf("%s is following:", currentUser.Login)
@@ -83,8 +78,9 @@ func TestGetFollowing(t *testing.T) {
 }
 
 func TestIsFollowing(t *testing.T) {
+	tgh := replaySession(t)
 	u := "octocat"
-	if followingp, err := tgh.IsFollowing(u); err != nil {
+	if followingp, _, err := tgh.IsFollowing(u); err != nil {
 		t.Error(err)
 	} else if followingp {
 		t.Logf("The current user is following %s", u)
@@ -94,11 +90,12 @@ func TestIsFollowing(t *testing.T) {
 }
 
 func TestFollow(t *testing.T) {
+	tgh := replaySession(t)
 	u := "octocat"
-	if err := tgh.Follow(u); err != nil {
+	if _, err := tgh.Follow(u); err != nil {
 		t.Error(err)
 	} else {
-		if following, err := tgh.IsFollowing(u); err != nil {
+		if following, _, err := tgh.IsFollowing(u); err != nil {
 			t.Error(err)
 		} else if following {
 			t.Logf("You are now following %s", u)
@@ -109,11 +106,12 @@ func TestFollow(t *testing.T) {
 }
 
 func TestUnfollow(t *testing.T) {
+	tgh := replaySession(t)
 	u := "octocat"
-	if err := tgh.Unfollow(u); err != nil {
+	if _, err := tgh.Unfollow(u); err != nil {
 		t.Error(err)
 	} else {
-		if following, err := tgh.IsFollowing(u); err != nil {
+		if following, _, err := tgh.IsFollowing(u); err != nil {
 			t.Error(err)
 		} else if !following {
 			t.Logf("You are no longer following %s", u)
@@ -124,8 +122,13 @@ func TestUnfollow(t *testing.T) {
 }
 
 func TestGetPublicKeys(t *testing.T) {
-	user, _ := tgh.GetCurrentUser()
-	keys, err := user.GetPublicKeys()
+	tgh := replaySession(t)
+	user, _, err := tgh.GetCurrentUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, _, err := user.GetPublicKeys()
 	if err != nil {
 		t.Error(err)
 	} else {
@@ -137,7 +140,8 @@ func TestGetPublicKeys(t *testing.T) {
 }
 
 func TestCurrentUserPublicKeys(t *testing.T) {
-	if keys, err := tgh.PublicKeys(); err != nil {
+	tgh := replaySession(t)
+	if keys, _, err := tgh.PublicKeys(); err != nil {
 		t.Error(err)
 	} else {
 		t.Logf("You have the following public keys:")
@@ -148,12 +152,15 @@ func TestCurrentUserPublicKeys(t *testing.T) {
 }
 
 func TestGetSinglePublicKey(t *testing.T) {
-	keys, err := tgh.PublicKeys()
+	tgh := replaySession(t)
+	keys, _, err := tgh.PublicKeys()
 	if err != nil {
 		t.Error("Could not fetch the current user's public keys")
 		t.Error(err)
+	} else if len(keys) == 0 {
+		t.Error("The current user has no public keys")
 	} else {
-		key, err := tgh.GetPublicKey(keys[0].Id)
+		key, _, err := tgh.GetPublicKey(keys[0].Id)
 		if err != nil {
 			t.Error(err)
 		} else {
@@ -162,23 +169,30 @@ func TestGetSinglePublicKey(t *testing.T) {
 	}
 }
 
+// Adds a test key, then removes it again, so that recording the test leaves
+// the account as it was.
 func TestAddPublicKey(t *testing.T) {
+	tgh := replaySession(t)
 	title := "gothub test key"
-	if newKeyId, err := tgh.AddPublicKey(title, testSshKeys[0]); err != nil {
+	newKeyId, _, err := tgh.AddPublicKey(title, testSshKeys[0])
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	t.Logf("Created new key \"%s\": %d", title, newKeyId)
+
+	if _, err := tgh.RemovePublicKey(newKeyId); err != nil {
 		t.Errorf("%s", err)
-	} else {
-		t.Logf("Created new key \"%s\": %d", title, newKeyId)
-		testKeyId = newKeyId
 	}
 }
 
 func TestRemovePublicKey(t *testing.T) {
-	if testKeyId == 0 {
-		t.Errorf("The test key ID was not set by the TestAddPublicKey test")
-		return
+	tgh := replaySession(t)
+	testKeyId, _, err := tgh.AddPublicKey("gothub test key", testSshKeys[1])
+	if err != nil {
+		t.Fatalf("Could not add a key to remove: %s", err)
 	}
 
-	if err := tgh.RemovePublicKey(testKeyId); err != nil {
+	if _, err := tgh.RemovePublicKey(testKeyId); err != nil {
 		t.Errorf("%s", err)
 	} else {
 		t.Logf("Successfully removed public key %d", testKeyId)
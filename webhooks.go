@@ -0,0 +1,225 @@
+package gothub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// GitHub caps webhook payloads at 25 MB.
+const maxWebhookPayload = 25 << 20
+
+var (
+	ErrInvalidSignature = errors.New("Webhook signature does not match the payload")
+	ErrMissingSignature = errors.New("Webhook delivery is not signed")
+	ErrNoWebhookSecret  = errors.New("Webhook secret is empty")
+)
+
+// A webhook delivery, as described here:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#delivery-headers
+type Delivery struct {
+	// The delivery's GUID, from X-GitHub-Delivery, and the event it is for,
+	// from X-GitHub-Event (e.g. "push").
+	Id    string
+	Event string
+
+	// The ID of the webhook that sent it, from X-GitHub-Hook-ID.
+	HookId string
+
+	// The payload as it was sent, and decoded into the struct for its event
+	// (e.g. *PushEvent). Data is nil for events that there is no struct for;
+	// their payloads can still be unmarshalled into one of your own.
+	Payload json.RawMessage
+	Data    WebhookEvent
+}
+
+// Reports whether the signature, as sent in the X-Hub-Signature-256 header,
+// is that of the payload with the secret. The signatures are compared in
+// constant time.
+func ValidateSignature(payload []byte, signature, secret string) error {
+	if signature == "" {
+		return ErrMissingSignature
+	}
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Reads a webhook delivery from the request, checking its signature against
+// the secret, and decodes its payload. Payloads may be sent either as JSON, or
+// as a form (with the JSON in its "payload" field). An empty secret is an
+// error (ErrNoWebhookSecret), rather than a way to skip the check; see
+// ParseUnverifiedWebhook for that.
+func ParseWebhook(r *http.Request, secret string) (*Delivery, error) {
+	if secret == "" {
+		return nil, ErrNoWebhookSecret
+	}
+	return parseWebhook(r, secret, true)
+}
+
+// Like ParseWebhook, but the delivery's signature is not checked at all,
+// which is only safe when nobody but GitHub can reach the endpoint.
+func ParseUnverifiedWebhook(r *http.Request) (*Delivery, error) {
+	return parseWebhook(r, "", false)
+}
+
+func parseWebhook(r *http.Request, secret string, verify bool) (*Delivery, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxWebhookPayload))
+	if err != nil {
+		return nil, err
+	}
+	if verify {
+		if err := ValidateSignature(body, r.Header.Get("X-Hub-Signature-256"), secret); err != nil {
+			return nil, err
+		}
+	}
+
+	d := &Delivery{
+		Id:      r.Header.Get("X-GitHub-Delivery"),
+		Event:   r.Header.Get("X-GitHub-Event"),
+		HookId:  r.Header.Get("X-GitHub-Hook-ID"),
+		Payload: body,
+	}
+	if d.Event == "" {
+		return nil, errors.New("Webhook delivery has no X-GitHub-Event header")
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = json.RawMessage(form.Get("payload"))
+	}
+
+	if newEvent, ok := webhookEvents[d.Event]; ok {
+		d.Data = newEvent()
+		if err := json.Unmarshal(d.Payload, d.Data); err != nil {
+			return nil, fmt.Errorf("Cannot decode %s webhook payload: %w", d.Event, err)
+		}
+	}
+	return d, nil
+}
+
+/*
+Receives GitHub's webhook deliveries, and hands each of them to the
+functions registered for its event:
+
+	h := gothub.NewWebhookHandler(os.Getenv("WEBHOOK_SECRET"))
+	gothub.OnEvent(h, func(ctx context.Context, d *gothub.Delivery, e *gothub.PushEvent) error {
+	    log.Printf("%s pushed %d commits to %s", e.Pusher.Name, len(e.Commits), e.Ref)
+	    return nil
+	})
+	http.Handle("/webhooks", h)
+
+Deliveries whose X-Hub-Signature-256 header does not match their payload are
+turned away with 401 Unauthorized, and those that cannot be read with 400 Bad
+Request. Should one of the functions fail, GitHub gets a 500 Internal Server
+Error, without the error's message, which is not for outsiders to see; log
+errors in the functions themselves if you want to keep them. Otherwise GitHub
+gets a 204 No Content, whether or not any function was registered for the
+event. A handler created with an empty secret turns every delivery away with
+a 500 Internal Server Error.
+
+The functions are called in the order they were registered, one after
+another, before the response is sent. GitHub gives up on deliveries that
+take longer than ten seconds to respond to, so anything slow should be done
+in the background.
+*/
+type WebhookHandler struct {
+	secret string
+	verify bool
+
+	mu       sync.RWMutex
+	handlers map[string][]func(ctx context.Context, d *Delivery) error
+}
+
+// Creates a WebhookHandler that checks the signatures of deliveries against
+// the webhook's secret.
+func NewWebhookHandler(secret string) *WebhookHandler {
+	return &WebhookHandler{secret: secret, verify: true, handlers: make(map[string][]func(ctx context.Context, d *Delivery) error)}
+}
+
+// Creates a WebhookHandler that does not check the signatures of deliveries
+// at all, which is only safe when nobody but GitHub can reach it.
+func NewUnverifiedWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{handlers: make(map[string][]func(ctx context.Context, d *Delivery) error)}
+}
+
+// Registers f for deliveries of the event (e.g. "push"), or of every event,
+// if it is "*".
+func (h *WebhookHandler) HandleFunc(event string, f func(ctx context.Context, d *Delivery) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[event] = append(h.handlers[event], f)
+}
+
+// Registers f for deliveries of the event whose payload E is the struct for
+// (e.g. *PushEvent for "push").
+func OnEvent[E WebhookEvent](h *WebhookHandler, f func(ctx context.Context, d *Delivery, e E) error) {
+	var zero E
+	h.HandleFunc(zero.EventName(), func(ctx context.Context, d *Delivery) error {
+		return f(ctx, d, d.Data.(E))
+	})
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		d   *Delivery
+		err error
+	)
+	if h.verify {
+		d, err = ParseWebhook(r, h.secret)
+	} else {
+		d, err = ParseUnverifiedWebhook(r)
+	}
+	switch {
+	case errors.Is(err, ErrNoWebhookSecret):
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	case errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrMissingSignature):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.mu.RLock()
+	var handlers []func(ctx context.Context, d *Delivery) error
+	handlers = append(handlers, h.handlers[d.Event]...)
+	handlers = append(handlers, h.handlers["*"]...)
+	h.mu.RUnlock()
+
+	for _, f := range handlers {
+		if err := f(r.Context(), d); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,178 @@
+package gothub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const webhookSecret = "It's a Secret to Everybody"
+
+func sign(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDelivery(event, payload, signature string) *http.Request {
+	r := httptest.NewRequest("POST", "/webhooks", strings.NewReader(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	r.Header.Set("X-GitHub-Hook-ID", "292430182")
+	if signature != "" {
+		r.Header.Set("X-Hub-Signature-256", signature)
+	}
+	return r
+}
+
+func TestValidateSignature(t *testing.T) {
+	// The example from GitHub's documentation.
+	err := ValidateSignature([]byte("Hello, World!"), "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", webhookSecret)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		signature string
+		err       error
+	}{
+		{"", ErrMissingSignature},
+		{"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e18", ErrInvalidSignature},
+		{"sha1=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", ErrInvalidSignature},
+		{"sha256=not hex", ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		if err := ValidateSignature([]byte("Hello, World!"), test.signature, webhookSecret); err != test.err {
+			t.Errorf("%q: expected %v, got %v", test.signature, test.err, err)
+		}
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	h := NewWebhookHandler(webhookSecret)
+
+	var pushes, all []string
+	OnEvent(h, func(ctx context.Context, d *Delivery, e *PushEvent) error {
+		pushes = append(pushes, d.Id+" "+e.Ref+" "+e.Repository.FullName)
+		return nil
+	})
+	OnEvent(h, func(ctx context.Context, d *Delivery, e *IssuesEvent) error {
+		return errors.New("Cannot handle issues")
+	})
+	h.HandleFunc("*", func(ctx context.Context, d *Delivery) error {
+		all = append(all, d.Event)
+		return nil
+	})
+
+	payload := `{"ref": "refs/heads/main", "repository": {"full_name": "octocat/hello-world", "created_at": 1402950020, "pushed_at": 1700000000, "updated_at": "2023-11-14T22:13:20Z"}}`
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+	}{
+		{"push", newDelivery("push", payload, sign(payload, webhookSecret)), http.StatusNoContent},
+		{"unknown event", newDelivery("sponsorship", `{"action": "created"}`, sign(`{"action": "created"}`, webhookSecret)), http.StatusNoContent},
+		{"failing handler", newDelivery("issues", `{"action": "opened"}`, sign(`{"action": "opened"}`, webhookSecret)), http.StatusInternalServerError},
+		{"bad signature", newDelivery("push", payload, sign(payload, "guess")), http.StatusUnauthorized},
+		{"unsigned", newDelivery("push", payload, ""), http.StatusUnauthorized},
+		{"no event", newDelivery("", payload, sign(payload, webhookSecret)), http.StatusBadRequest},
+		{"bad payload", newDelivery("push", `{"ref": 1}`, sign(`{"ref": 1}`, webhookSecret)), http.StatusBadRequest},
+		{"GET", httptest.NewRequest("GET", "/webhooks", nil), http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, test.request)
+		if w.Code != test.status {
+			t.Errorf("%s: expected %d, got %d (%s)", test.name, test.status, w.Code, w.Body)
+		}
+	}
+
+	if len(pushes) != 1 || pushes[0] != "72d3162e-cc78-11e3-81ab-4c9367dc0958 refs/heads/main octocat/hello-world" {
+		t.Errorf("Unexpected pushes %q", pushes)
+	}
+	if strings.Join(all, ",") != "push,sponsorship" {
+		t.Errorf("Expected every event to be handled by the catch-all, got %q", all)
+	}
+
+	// The failing function's error is not sent back.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newDelivery("issues", `{"action": "opened"}`, sign(`{"action": "opened"}`, webhookSecret)))
+	if strings.Contains(w.Body.String(), "Cannot handle issues") {
+		t.Errorf("Expected the function's error to be kept from GitHub, got %q", w.Body)
+	}
+}
+
+func TestParseWebhookForm(t *testing.T) {
+	payload := `{"action": "published", "release": {"tag_name": "v1.0.0", "author": {"login": "octocat"}}, "sender": {"login": "hubot"}}`
+	body := url.Values{"payload": {payload}}.Encode()
+	r := newDelivery("release", body, sign(body, webhookSecret))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	d, err := ParseWebhook(r, webhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := d.Data.(*ReleaseEvent)
+	if !ok {
+		t.Fatalf("Expected a *ReleaseEvent, got %T", d.Data)
+	}
+	if e.Action != "published" || e.Release.TagName != "v1.0.0" || e.Release.Author.Login != "octocat" || e.Sender.Login != "hubot" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if string(d.Payload) != payload || d.HookId != "292430182" {
+		t.Errorf("Unexpected delivery %+v", d)
+	}
+
+	// An empty secret does not skip the check; only ParseUnverifiedWebhook
+	// does.
+	r = newDelivery("release", payload, "")
+	if _, err := ParseWebhook(r, ""); !errors.Is(err, ErrNoWebhookSecret) {
+		t.Errorf("Expected ErrNoWebhookSecret, got %v", err)
+	}
+	r = newDelivery("release", payload, "")
+	if _, err := ParseUnverifiedWebhook(r); err != nil {
+		t.Error(err)
+	}
+
+	// Payloads that do not fit their event keep the reason why.
+	r = newDelivery("release", `{"release": []}`, sign(`{"release": []}`, webhookSecret))
+	var typeErr *json.UnmarshalTypeError
+	if _, err := ParseWebhook(r, webhookSecret); !errors.As(err, &typeErr) {
+		t.Errorf("Expected a *json.UnmarshalTypeError, got %v", err)
+	}
+}
+
+func TestWebhookHandlerSecret(t *testing.T) {
+	payload := `{"action": "created"}`
+
+	var handled int
+	count := func(ctx context.Context, d *Delivery) error {
+		handled++
+		return nil
+	}
+
+	h := NewWebhookHandler("")
+	h.HandleFunc("*", count)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newDelivery("sponsorship", payload, sign(payload, "")))
+	if w.Code != http.StatusInternalServerError || handled != 0 {
+		t.Errorf("Expected a handler without a secret to refuse deliveries, got %d", w.Code)
+	}
+
+	h = NewUnverifiedWebhookHandler()
+	h.HandleFunc("*", count)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newDelivery("sponsorship", payload, ""))
+	if w.Code != http.StatusNoContent || handled != 1 {
+		t.Errorf("Expected an unverified handler to take unsigned deliveries, got %d", w.Code)
+	}
+}